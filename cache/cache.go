@@ -0,0 +1,186 @@
+// Package cache 提供进程内的本地缓存：分片加锁、按条目 TTL 过期，
+// 并支持按条目数或成本上限的 LRU / LFU / FIFO / W-TinyLFU 淘汰。
+package cache
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// Cache 是并发安全的泛型本地缓存。
+// key 经过哈希分散到多个分片，每个分片独立加锁，降低热点读写之间的锁竞争。
+type Cache[K comparable, V any] struct {
+	shards []*shard[K, V]
+	mask   uint64
+	seed   maphash.Seed
+
+	ttl  time.Duration
+	cost func(K, V) int64
+
+	stop      chan struct{} // 关闭后台清理协程
+	closeOnce sync.Once
+}
+
+// New 创建一个缓存
+func New[K comparable, V any](opts ...Option) *Cache[K, V] {
+	// 默认配置
+	cfg := DefaultOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+
+	// 分片数向上取整为 2 的幂；条目上限很小时减少分片，避免每个分片都被放大到至少 1 条
+	n := nextPowerOfTwo(cfg.Shards)
+	for n > 1 && cfg.MaxEntries > 0 && n > cfg.MaxEntries {
+		n >>= 1
+	}
+
+	c := &Cache[K, V]{
+		shards: make([]*shard[K, V], n),
+		mask:   uint64(n - 1),
+		seed:   maphash.MakeSeed(),
+		ttl:    cfg.TTL,
+		stop:   make(chan struct{}),
+	}
+
+	if cfg.cost != nil {
+		fn, ok := cfg.cost.(func(K, V) int64)
+		if !ok {
+			panic(fmt.Sprintf("cache: WithCost got %T, which does not match the cache key/value types", cfg.cost))
+		}
+		c.cost = fn
+	}
+	onEvict := callback[K, V]("WithOnEvict", cfg.onEvict)
+	onExpire := callback[K, V]("WithOnExpire", cfg.onExpire)
+
+	// 上限平均分到每个分片（向上取整）
+	maxEntries := ceilDiv(int64(cfg.MaxEntries), int64(n))
+	maxCost := ceilDiv(cfg.MaxCost, int64(n))
+	hash := func(key K) uint64 { return maphash.Comparable(c.seed, key) }
+	for i := range c.shards {
+		c.shards[i] = newShard[K, V](func() policy[K] {
+			return newPolicy[K](cfg.Policy, int(maxEntries), hash)
+		}, int(maxEntries), maxCost, onEvict, onExpire)
+	}
+
+	if cfg.CleanupInterval > 0 {
+		go c.janitor(cfg.CleanupInterval)
+	}
+	return c
+}
+
+// callback 校验 WithOnEvict、WithOnExpire 传入的回调类型
+func callback[K comparable, V any](name string, fn any) func(K, V) {
+	if fn == nil {
+		return nil
+	}
+	cb, ok := fn.(func(K, V))
+	if !ok {
+		panic(fmt.Sprintf("cache: %s got %T, which does not match the cache key/value types", name, fn))
+	}
+	return cb
+}
+
+// ceilDiv 向上取整除法，a <= 0 时返回 0
+func ceilDiv(a, b int64) int64 {
+	if a <= 0 {
+		return 0
+	}
+	return (a + b - 1) / b
+}
+
+// shardFor 计算 key 所在的分片
+func (c *Cache[K, V]) shardFor(key K) *shard[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)&c.mask]
+}
+
+// Get 读取 key，不存在或已过期时返回 false
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	return c.shardFor(key).get(key, time.Now().UnixNano())
+}
+
+// Set 使用默认 TTL 写入 key
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL 写入 key 并指定过期时间，ttl <= 0 表示永不过期
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	now := time.Now()
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = now.Add(ttl).UnixNano()
+	}
+	c.set(key, value, expireAt, now.UnixNano())
+}
+
+// set 按绝对过期时间写入
+func (c *Cache[K, V]) set(key K, value V, expireAt, now int64) {
+	cost := int64(1)
+	if c.cost != nil {
+		cost = c.cost(key, value)
+	}
+	c.shardFor(key).set(key, value, expireAt, cost, now)
+}
+
+// Delete 删除 key，返回删除前 key 是否存在且未过期
+func (c *Cache[K, V]) Delete(key K) bool {
+	return c.shardFor(key).delete(key, time.Now().UnixNano())
+}
+
+// Len 返回当前条目数（可能包含尚未被清理的过期条目）
+func (c *Cache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		n += s.len()
+	}
+	return n
+}
+
+// Stats 返回命中、未命中、淘汰与过期计数，计数从创建起累计，Clear 不会重置
+func (c *Cache[K, V]) Stats() Stats {
+	var st Stats
+	for _, s := range c.shards {
+		st = st.Add(s.stats())
+	}
+	return st
+}
+
+// Clear 清空所有条目
+func (c *Cache[K, V]) Clear() {
+	for _, s := range c.shards {
+		s.clear()
+	}
+}
+
+// DeleteExpired 立即清理所有已过期的条目
+func (c *Cache[K, V]) DeleteExpired() {
+	now := time.Now().UnixNano()
+	for _, s := range c.shards {
+		s.deleteExpired(now)
+	}
+}
+
+// Close 停止后台清理协程，可重复调用
+func (c *Cache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// janitor 定期清理过期条目，直到 Close 被调用
+func (c *Cache[K, V]) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.DeleteExpired()
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 基础读写删除
+func TestSetGetDelete(t *testing.T) {
+	c := New[string, int]()
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("empty cache should miss")
+	}
+
+	c.Set("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("unexpected get: %v %v", v, ok)
+	}
+
+	c.Set("a", 2)
+	if v, _ := c.Get("a"); v != 2 {
+		t.Fatalf("overwrite should update value, got %v", v)
+	}
+
+	if !c.Delete("a") {
+		t.Fatalf("delete should report existing key")
+	}
+	if c.Delete("a") {
+		t.Fatalf("second delete should report missing key")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("cache should be empty, got %d", c.Len())
+	}
+}
+
+// 测试按条目设置 TTL
+func TestTTL(t *testing.T) {
+	c := New[string, string](WithTTL(20 * time.Millisecond))
+
+	c.Set("short", "x")
+	c.SetWithTTL("forever", "y", 0)
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok := c.Get("short"); ok {
+		t.Fatalf("entry should expire with default ttl")
+	}
+	if v, ok := c.Get("forever"); !ok || v != "y" {
+		t.Fatalf("entry without ttl should survive, got %v %v", v, ok)
+	}
+}
+
+// 测试后台清理协程
+func TestCleanupInterval(t *testing.T) {
+	c := New[int, int](WithCleanupInterval(10 * time.Millisecond))
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		c.SetWithTTL(i, i, 5*time.Millisecond)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for c.Len() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor should remove expired entries, still %d", c.Len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 测试条目数上限
+func TestMaxEntries(t *testing.T) {
+	c := New[int, int](WithMaxEntries(100))
+
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i)
+	}
+	// 上限按分片向上取整，总量不会超过 上限 + 分片数
+	if n := c.Len(); n > 100+len(c.shards) {
+		t.Fatalf("cache should be bounded, got %d entries", n)
+	}
+}
+
+// 测试成本上限
+func TestMaxCost(t *testing.T) {
+	c := New[string, []byte](
+		WithShards(1),
+		WithMaxCost(10),
+		WithCost(func(_ string, v []byte) int64 { return int64(len(v)) }),
+	)
+
+	c.Set("a", make([]byte, 4))
+	c.Set("b", make([]byte, 4))
+	c.Set("c", make([]byte, 4)) // 总成本 12 > 10，淘汰最久未使用的 a
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("a should be evicted by cost")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatalf("c should be present")
+	}
+
+	// 单个条目超过上限时直接被丢弃
+	c.Set("huge", make([]byte, 11))
+	if _, ok := c.Get("huge"); ok {
+		t.Fatalf("entry larger than MaxCost should not be kept")
+	}
+}
+
+// 测试 WithCost 类型不匹配时 panic
+func TestCostTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("mismatched cost func should panic")
+		}
+	}()
+	New[string, int](WithCost(func(int, int) int64 { return 1 }))
+}
+
+// 并发读写，配合 -race 使用
+func TestConcurrentAccess(t *testing.T) {
+	c := New[string, int](WithMaxEntries(64), WithPolicy(LFU))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				k := strconv.Itoa((g*31 + i) % 128)
+				c.Set(k, i)
+				c.Get(k)
+				if i%7 == 0 {
+					c.Delete(k)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	c.Clear()
+	if c.Len() != 0 {
+		t.Fatalf("clear should remove everything, got %d", c.Len())
+	}
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	c := New[int, int](WithMaxEntries(1 << 16))
+	for i := 0; i < 1<<16; i++ {
+		c.Set(i, i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(i & (1<<16 - 1))
+			i++
+		}
+	})
+}
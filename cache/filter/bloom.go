@@ -0,0 +1,214 @@
+// Package filter 提供用于防止缓存穿透的概率型集合：
+// Bloom / 可扩容 Bloom（只能添加）、Cuckoo（支持删除），以及多节点共享的 Redis 位图 Bloom。
+// 它们都可以回答“一定不存在”，在请求打到数据库之前挡掉随机伪造的 ID。
+package filter
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// 对外可见的一些错误
+var (
+	ErrInvalidData = errors.New("filter: invalid serialized data")
+)
+
+// 序列化格式的魔数，区分不同类型的过滤器
+const (
+	magicBloom    = 'B'
+	magicScalable = 'S'
+	magicCuckoo   = 'C'
+	formatVersion = 1
+)
+
+// maxHashes 哈希函数个数的上限，误判率低到 2^-64 也用不到更多
+const maxHashes = 64
+
+// Bloom 标准布隆过滤器，并发安全
+type Bloom struct {
+	mu    sync.RWMutex
+	bits  []uint64
+	m     uint64 // 位数
+	k     uint32 // 哈希函数个数
+	count uint64 // 已添加的元素个数（近似，重复添加也会计数）
+}
+
+// NewBloom 按预期元素个数 n 与误判率 fpRate 创建布隆过滤器
+func NewBloom(n uint64, fpRate float64) *Bloom {
+	m, k := estimateParameters(n, fpRate)
+	return newBloom(m, k)
+}
+
+func newBloom(m uint64, k uint32) *Bloom {
+	return &Bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// estimateParameters 计算最优位数 m = -n*ln(p)/ln2^2 与哈希个数 k = m/n*ln2
+func estimateParameters(n uint64, p float64) (uint64, uint32) {
+	if n == 0 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > maxHashes {
+		k = maxHashes
+	}
+	return m, k
+}
+
+// Add 添加元素
+func (b *Bloom) Add(data []byte) {
+	h1, h2 := baseHashes(data)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := uint32(0); i < b.k; i++ {
+		pos := location(h1, h2, i, b.m)
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+	b.count++
+}
+
+// Test 元素可能存在时返回 true；返回 false 表示一定不存在
+func (b *Bloom) Test(data []byte) bool {
+	h1, h2 := baseHashes(data)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for i := uint32(0); i < b.k; i++ {
+		pos := location(h1, h2, i, b.m)
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// TestAndAdd 检查后添加，返回添加前是否可能存在
+func (b *Bloom) TestAndAdd(data []byte) bool {
+	h1, h2 := baseHashes(data)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	present := true
+	for i := uint32(0); i < b.k; i++ {
+		pos := location(h1, h2, i, b.m)
+		word, bit := pos/64, uint64(1)<<(pos%64)
+		if b.bits[word]&bit == 0 {
+			present = false
+			b.bits[word] |= bit
+		}
+	}
+	b.count++
+	return present
+}
+
+// Count 已添加的元素个数
+func (b *Bloom) Count() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.count
+}
+
+// Cap 返回位数与哈希函数个数
+func (b *Bloom) Cap() (m uint64, k uint32) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.m, b.k
+}
+
+// MarshalBinary 实现 encoding.BinaryMarshaler
+func (b *Bloom) MarshalBinary() ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.appendBinary(nil), nil
+}
+
+// appendBinary 格式：magic(1) version(1) k(4) m(8) count(8) bits(8*n)
+func (b *Bloom) appendBinary(buf []byte) []byte {
+	buf = append(buf, magicBloom, formatVersion)
+	buf = binary.BigEndian.AppendUint32(buf, b.k)
+	buf = binary.BigEndian.AppendUint64(buf, b.m)
+	buf = binary.BigEndian.AppendUint64(buf, b.count)
+	for _, w := range b.bits {
+		buf = binary.BigEndian.AppendUint64(buf, w)
+	}
+	return buf
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler
+func (b *Bloom) UnmarshalBinary(data []byte) error {
+	nb, rest, err := decodeBloom(data)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return ErrInvalidData
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bits, b.m, b.k, b.count = nb.bits, nb.m, nb.k, nb.count
+	return nil
+}
+
+// decodeBloom 解析一个 Bloom，返回剩余的字节
+func decodeBloom(data []byte) (*Bloom, []byte, error) {
+	const header = 2 + 4 + 8 + 8
+	if len(data) < header || data[0] != magicBloom || data[1] != formatVersion {
+		return nil, nil, ErrInvalidData
+	}
+	k := binary.BigEndian.Uint32(data[2:])
+	m := binary.BigEndian.Uint64(data[6:])
+	count := binary.BigEndian.Uint64(data[14:])
+	// 先按数据长度校验 m，避免 m 接近 2^64 时 (m+63)/64 溢出得到 0 个字
+	words := m / 64
+	if m%64 != 0 {
+		words++
+	}
+	if k == 0 || k > maxHashes || m == 0 || words > uint64(len(data)-header)/8 {
+		return nil, nil, ErrInvalidData
+	}
+
+	b := newBloom(m, k)
+	b.count = count
+	p := data[header:]
+	for i := range b.bits {
+		b.bits[i] = binary.BigEndian.Uint64(p[i*8:])
+	}
+	return b, p[words*8:], nil
+}
+
+// baseHashes 对数据做一次 FNV-1a 128 位哈希，拆成两个 64 位值用于双重哈希。
+// 使用固定的哈希算法，保证序列化后在其他进程、其他节点上结果一致。
+// FNV 对短输入的雪崩效果较差，拆分后再各自做一次 murmur3 的 fmix64 混洗。
+func baseHashes(data []byte) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write(data)
+	var sum [16]byte
+	h.Sum(sum[:0])
+	return fmix64(binary.BigEndian.Uint64(sum[:8])), fmix64(binary.BigEndian.Uint64(sum[8:])) | 1
+}
+
+// fmix64 murmur3 的 64 位终结混洗
+func fmix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// location 第 i 个哈希函数对应的位置：h1 + i*h2 (mod m)
+func location(h1, h2 uint64, i uint32, m uint64) uint64 {
+	return (h1 + uint64(i)*h2) % m
+}
@@ -0,0 +1,207 @@
+package filter
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+)
+
+// Cuckoo 过滤器参数
+const (
+	bucketSize = 4   // 每个桶的槽位数
+	maxKicks   = 500 // 插入时最多踢出多少次
+)
+
+// bucket 一个桶，0 表示空槽
+type bucket [bucketSize]uint16
+
+// Cuckoo 布谷鸟过滤器：与 Bloom 相比支持删除，
+// 每个元素保存一个 16 位指纹，存放在两个候选桶之一，并发安全。
+// 删除一个从未添加过的元素可能误删指纹相同的其他元素，调用方需保证只删除添加过的元素。
+type Cuckoo struct {
+	mu      sync.RWMutex
+	buckets []bucket
+	mask    uint64
+	count   uint64
+}
+
+// NewCuckoo 创建能容纳约 capacity 个元素的布谷鸟过滤器
+func NewCuckoo(capacity uint64) *Cuckoo {
+	n := uint64(1)
+	for n*bucketSize < capacity {
+		n <<= 1
+	}
+	return &Cuckoo{buckets: make([]bucket, n), mask: n - 1}
+}
+
+// fingerprint 计算指纹与用于定位第一个候选桶的哈希，不访问过滤器的状态，可以在加锁之前计算
+func fingerprint(data []byte) (uint16, uint64) {
+	h1, h2 := baseHashes(data)
+	fp := uint16(h2 >> 48)
+	if fp == 0 {
+		fp = 1
+	}
+	return fp, h1
+}
+
+// index 哈希对应的第一个候选桶，调用方需持有锁（UnmarshalBinary 会替换 mask）
+func (c *Cuckoo) index(h uint64) uint64 {
+	return h & c.mask
+}
+
+// altIndex 另一个候选桶：i ^ hash(fp)，对两个桶都成立（互为 alt），调用方需持有锁
+func (c *Cuckoo) altIndex(i uint64, fp uint16) uint64 {
+	h := uint64(fp) * 0x5bd1e995
+	return (i ^ h) & c.mask
+}
+
+// Add 添加元素，过滤器过满无法放下时返回 false
+func (c *Cuckoo) Add(data []byte) bool {
+	fp, h := fingerprint(data)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i1 := c.index(h)
+	i2 := c.altIndex(i1, fp)
+	if c.insert(i1, fp) || c.insert(i2, fp) {
+		c.count++
+		return true
+	}
+
+	// 两个桶都满：随机踢出一个指纹，把它挪到它的另一个桶
+	i := i1
+	if rand.Intn(2) == 0 {
+		i = i2
+	}
+	// 记录踢出路径，失败时回滚，保证已有元素不会丢失
+	type kick struct {
+		i    uint64
+		slot int
+		fp   uint16
+	}
+	var path []kick
+	for n := 0; n < maxKicks; n++ {
+		slot := rand.Intn(bucketSize)
+		old := c.buckets[i][slot]
+		c.buckets[i][slot] = fp
+		path = append(path, kick{i: i, slot: slot, fp: old})
+
+		fp = old
+		i = c.altIndex(i, fp)
+		if c.insert(i, fp) {
+			c.count++
+			return true
+		}
+	}
+	for j := len(path) - 1; j >= 0; j-- {
+		c.buckets[path[j].i][path[j].slot] = path[j].fp
+	}
+	return false
+}
+
+// insert 把指纹放进桶 i 的空槽
+func (c *Cuckoo) insert(i uint64, fp uint16) bool {
+	b := &c.buckets[i]
+	for s := range b {
+		if b[s] == 0 {
+			b[s] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// Test 元素可能存在时返回 true；返回 false 表示一定不存在
+func (c *Cuckoo) Test(data []byte) bool {
+	fp, h := fingerprint(data)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i1 := c.index(h)
+	return c.buckets[i1].contains(fp) || c.buckets[c.altIndex(i1, fp)].contains(fp)
+}
+
+// Delete 删除一个元素（的一份指纹），返回是否找到
+func (c *Cuckoo) Delete(data []byte) bool {
+	fp, h := fingerprint(data)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i1 := c.index(h)
+	if c.buckets[i1].remove(fp) || c.buckets[c.altIndex(i1, fp)].remove(fp) {
+		c.count--
+		return true
+	}
+	return false
+}
+
+// Count 当前元素个数
+func (c *Cuckoo) Count() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.count
+}
+
+func (b *bucket) contains(fp uint16) bool {
+	for _, v := range b {
+		if v == fp {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bucket) remove(fp uint16) bool {
+	for s, v := range b {
+		if v == fp {
+			b[s] = 0
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalBinary 实现 encoding.BinaryMarshaler
+// 格式：magic(1) version(1) buckets(8) count(8) 然后是所有指纹
+func (c *Cuckoo) MarshalBinary() ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	buf := make([]byte, 0, 18+len(c.buckets)*bucketSize*2)
+	buf = append(buf, magicCuckoo, formatVersion)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(c.buckets)))
+	buf = binary.BigEndian.AppendUint64(buf, c.count)
+	for _, b := range c.buckets {
+		for _, fp := range b {
+			buf = binary.BigEndian.AppendUint16(buf, fp)
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler
+func (c *Cuckoo) UnmarshalBinary(data []byte) error {
+	const header = 2 + 8 + 8
+	if len(data) < header || data[0] != magicCuckoo || data[1] != formatVersion {
+		return ErrInvalidData
+	}
+	n := binary.BigEndian.Uint64(data[2:])
+	count := binary.BigEndian.Uint64(data[10:])
+	// 桶数必须是 2 的幂，且数据长度匹配；先用除法比较，避免 n*bucketSize*2 溢出
+	size := uint64(len(data) - header)
+	if n == 0 || n&(n-1) != 0 || n > size/(bucketSize*2) || size != n*bucketSize*2 {
+		return ErrInvalidData
+	}
+
+	buckets := make([]bucket, n)
+	p := data[header:]
+	for i := range buckets {
+		for s := 0; s < bucketSize; s++ {
+			buckets[i][s] = binary.BigEndian.Uint16(p)
+			p = p[2:]
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buckets, c.mask, c.count = buckets, n-1, count
+	return nil
+}
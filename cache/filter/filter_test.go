@@ -0,0 +1,286 @@
+package filter
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func key(i int) []byte { return []byte("id:" + strconv.Itoa(i)) }
+
+// falsePositiveRate 统计从未添加过的元素被误判的比例
+func falsePositiveRate(test func([]byte) bool, from, n int) float64 {
+	fp := 0
+	for i := from; i < from+n; i++ {
+		if test(key(i)) {
+			fp++
+		}
+	}
+	return float64(fp) / float64(n)
+}
+
+// 测试 Bloom：已添加的元素一定命中，误判率接近设定值
+func TestBloom(t *testing.T) {
+	const n = 10000
+	b := NewBloom(n, 0.01)
+	for i := 0; i < n; i++ {
+		b.Add(key(i))
+	}
+	for i := 0; i < n; i++ {
+		if !b.Test(key(i)) {
+			t.Fatalf("added element %d must be reported as present", i)
+		}
+	}
+	if rate := falsePositiveRate(b.Test, n, n); rate > 0.02 {
+		t.Fatalf("false positive rate too high: %.4f", rate)
+	}
+	if b.TestAndAdd(key(0)) != true || b.TestAndAdd([]byte("fresh")) != false {
+		t.Fatalf("unexpected TestAndAdd result")
+	}
+}
+
+// 测试 Bloom 序列化
+func TestBloomMarshal(t *testing.T) {
+	b := NewBloom(1000, 0.01)
+	for i := 0; i < 500; i++ {
+		b.Add(key(i))
+	}
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var restored Bloom
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		if !restored.Test(key(i)) {
+			t.Fatalf("restored filter lost element %d", i)
+		}
+	}
+	if restored.Count() != 500 {
+		t.Fatalf("unexpected count: %d", restored.Count())
+	}
+	if err := restored.UnmarshalBinary(data[:10]); err != ErrInvalidData {
+		t.Fatalf("truncated data should be rejected, got %v", err)
+	}
+}
+
+// 测试可扩容 Bloom：超出初始容量后自动扩容，误判率依然受控
+func TestScalableBloom(t *testing.T) {
+	const n = 20000
+	s := NewScalableBloom(1000, 0.01)
+	for i := 0; i < n; i++ {
+		s.Add(key(i))
+	}
+	if s.Filters() < 2 {
+		t.Fatalf("filter should have grown, got %d layers", s.Filters())
+	}
+	for i := 0; i < n; i++ {
+		if !s.Test(key(i)) {
+			t.Fatalf("added element %d must be reported as present", i)
+		}
+	}
+	if rate := falsePositiveRate(s.Test, n, n); rate > 0.02 {
+		t.Fatalf("false positive rate too high: %.4f", rate)
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var restored ScalableBloom
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if restored.Filters() != s.Filters() || restored.Count() != s.Count() {
+		t.Fatalf("restored filter differs: layers %d/%d count %d/%d",
+			restored.Filters(), s.Filters(), restored.Count(), s.Count())
+	}
+	// 恢复后继续写入，依然能正确扩容
+	for i := n; i < 2*n; i++ {
+		restored.Add(key(i))
+	}
+	if !restored.Test(key(2*n - 1)) {
+		t.Fatalf("restored filter should accept new elements")
+	}
+}
+
+// 测试伪造的头部：位数或桶数溢出、哈希个数过大、层容量与参数不符、超大的初始容量都要在分配内存之前拒绝
+func TestUnmarshalForged(t *testing.T) {
+	// m = 2^64-1 时 (m+63)/64 会溢出为 0
+	forged := []byte{magicBloom, formatVersion}
+	forged = binary.BigEndian.AppendUint32(forged, 3)
+	forged = binary.BigEndian.AppendUint64(forged, math.MaxUint64)
+	forged = binary.BigEndian.AppendUint64(forged, 0)
+	var b Bloom
+	if err := b.UnmarshalBinary(forged); err != ErrInvalidData {
+		t.Fatalf("overflowing bit count should be rejected, got %v", err)
+	}
+
+	// 哈希函数个数过大时每次 Test 都要空转
+	forged = []byte{magicBloom, formatVersion}
+	forged = binary.BigEndian.AppendUint32(forged, math.MaxUint32)
+	forged = binary.BigEndian.AppendUint64(forged, 64)
+	forged = binary.BigEndian.AppendUint64(forged, 0)
+	forged = binary.BigEndian.AppendUint64(forged, 0)
+	if err := b.UnmarshalBinary(forged); err != ErrInvalidData {
+		t.Fatalf("huge hash count should be rejected, got %v", err)
+	}
+
+	// Cuckoo 桶数为 2^61 时 n*bucketSize*2 溢出，不能据此分配
+	forged = []byte{magicCuckoo, formatVersion}
+	forged = binary.BigEndian.AppendUint64(forged, 1<<61)
+	forged = binary.BigEndian.AppendUint64(forged, 0)
+	var c Cuckoo
+	if err := c.UnmarshalBinary(forged); err != ErrInvalidData {
+		t.Fatalf("overflowing bucket count should be rejected, got %v", err)
+	}
+
+	layer := NewBloom(1000, 0.002).appendBinary(nil)
+	header := func(initial uint64, count uint32) []byte {
+		buf := []byte{magicScalable, formatVersion}
+		buf = binary.BigEndian.AppendUint64(buf, initial)
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(0.01))
+		return binary.BigEndian.AppendUint32(buf, count)
+	}
+	var s ScalableBloom
+	if err := s.UnmarshalBinary(append(header(1000, 1), layer...)); err != nil {
+		t.Fatalf("valid data rejected: %v", err)
+	}
+	// 层的位数与 initial 推算的不一致
+	if err := s.UnmarshalBinary(append(header(5000, 1), layer...)); err != ErrInvalidData {
+		t.Fatalf("mismatched layer should be rejected, got %v", err)
+	}
+	if err := s.UnmarshalBinary(append(header(math.MaxUint64/2, 1), layer...)); err != ErrInvalidData {
+		t.Fatalf("huge initial capacity should be rejected, got %v", err)
+	}
+	if err := s.UnmarshalBinary(append(header(1000, math.MaxUint32), layer...)); err != ErrInvalidData {
+		t.Fatalf("forged layer count should be rejected, got %v", err)
+	}
+}
+
+// 测试 Cuckoo：添加、删除、序列化
+func TestCuckoo(t *testing.T) {
+	const n = 5000
+	c := NewCuckoo(n * 2)
+	for i := 0; i < n; i++ {
+		if !c.Add(key(i)) {
+			t.Fatalf("add %d failed", i)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !c.Test(key(i)) {
+			t.Fatalf("added element %d must be reported as present", i)
+		}
+	}
+	if rate := falsePositiveRate(c.Test, n, n); rate > 0.01 {
+		t.Fatalf("false positive rate too high: %.4f", rate)
+	}
+
+	for i := 0; i < n; i += 2 {
+		if !c.Delete(key(i)) {
+			t.Fatalf("delete %d failed", i)
+		}
+	}
+	if c.Count() != n/2 {
+		t.Fatalf("unexpected count after delete: %d", c.Count())
+	}
+	for i := 1; i < n; i += 2 {
+		if !c.Test(key(i)) {
+			t.Fatalf("element %d should survive deleting others", i)
+		}
+	}
+
+	data, err := c.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var restored Cuckoo
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	for i := 1; i < n; i += 2 {
+		if !restored.Test(key(i)) {
+			t.Fatalf("restored filter lost element %d", i)
+		}
+	}
+}
+
+// 过滤器满了之后 Add 返回 false，且不会丢失已有元素
+func TestCuckooFull(t *testing.T) {
+	c := NewCuckoo(64)
+	added := 0
+	for i := 0; i < 1000; i++ {
+		if !c.Add(key(i)) {
+			break
+		}
+		added++
+	}
+	if added == 1000 {
+		t.Fatalf("small filter should eventually be full")
+	}
+	for i := 0; i < added; i++ {
+		if !c.Test(key(i)) {
+			t.Fatalf("element %d lost after failed insert", i)
+		}
+	}
+}
+
+// 位数超过 Redis 位图上限时截断，并按截断后的位数重新选取哈希个数
+func TestRedisBloomClamp(t *testing.T) {
+	n := uint64(1e9)
+	b := NewRedisBloom(nil, "bf", n, 0.01)
+	if b.m != 1<<32 {
+		t.Fatalf("bit count should be clamped to 2^32, got %d", b.m)
+	}
+	if want := uint32(math.Round(float64(b.m) / float64(n) * math.Ln2)); b.k != want {
+		t.Fatalf("hash count should be recomputed for the clamped size, want %d got %d", want, b.k)
+	}
+}
+
+// 测试 Redis 位图 Bloom：两个节点共享同一个过滤器，且与本地 Bloom 兼容
+func TestRedisBloom(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+	newNode := func() *RedisBloom {
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+		return NewRedisBloom(rdb, "bf:users", 1000, 0.01)
+	}
+	a, b := newNode(), newNode()
+
+	for i := 0; i < 100; i++ {
+		if err := a.Add(ctx, key(i)); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		ok, err := b.Test(ctx, key(i))
+		if err != nil || !ok {
+			t.Fatalf("element %d should be visible on other node: %v %v", i, ok, err)
+		}
+	}
+	if ok, _ := b.Test(ctx, []byte("never-added")); ok {
+		t.Logf("false positive on a single probe, acceptable but unlikely")
+	}
+
+	// 用本地 Bloom 覆盖远端位图
+	local := NewBloom(1000, 0.01)
+	local.Add([]byte("from-local"))
+	if err := a.Load(ctx, local); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if ok, _ := b.Test(ctx, []byte("from-local")); !ok {
+		t.Fatalf("element from local filter should be present")
+	}
+	if err := a.Load(ctx, NewBloom(10, 0.5)); err != ErrInvalidData {
+		t.Fatalf("mismatched filter should be rejected, got %v", err)
+	}
+}
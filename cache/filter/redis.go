@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"context"
+	"math"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBloom 基于 Redis 位图的布隆过滤器，多个节点使用同一个 key 即可共享过滤器。
+// 位图大小受 Redis 字符串上限（512MB，即 2^32 位）限制。
+type RedisBloom struct {
+	rdb *redis.Client
+	key string
+	m   uint64
+	k   uint32
+}
+
+// NewRedisBloom 按预期元素个数 n 与误判率 fpRate 创建；所有节点必须使用相同的 n 与 fpRate。
+// 所需位数超过 2^32 时截断为 2^32，并按截断后的位数重新选取 k，
+// 此时实际误判率约为 (1-e^(-kn/m))^k，高于 fpRate
+func NewRedisBloom(rdb *redis.Client, key string, n uint64, fpRate float64) *RedisBloom {
+	m, k := estimateParameters(n, fpRate)
+	if m > 1<<32 {
+		m = 1 << 32
+		k = uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+		if k < 1 {
+			k = 1
+		}
+	}
+	return &RedisBloom{rdb: rdb, key: key, m: m, k: k}
+}
+
+// offsets 计算元素对应的 k 个位偏移，与本地 Bloom 使用相同的哈希
+func (b *RedisBloom) offsets(data []byte) []int64 {
+	h1, h2 := baseHashes(data)
+	offs := make([]int64, b.k)
+	for i := range offs {
+		offs[i] = int64(location(h1, h2, uint32(i), b.m))
+	}
+	return offs
+}
+
+// Add 添加元素，k 个 SETBIT 通过一次 pipeline 发送
+func (b *RedisBloom) Add(ctx context.Context, data []byte) error {
+	_, err := b.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, off := range b.offsets(data) {
+			p.SetBit(ctx, b.key, off, 1)
+		}
+		return nil
+	})
+	return err
+}
+
+// Test 元素可能存在时返回 true；返回 false 表示一定不存在
+func (b *RedisBloom) Test(ctx context.Context, data []byte) (bool, error) {
+	offs := b.offsets(data)
+	cmds := make([]*redis.IntCmd, len(offs))
+	_, err := b.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, off := range offs {
+			cmds[i] = p.GetBit(ctx, b.key, off)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, cmd := range cmds {
+		if cmd.Val() == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Load 用本地 Bloom 的内容覆盖 Redis 中的位图，便于从离线构建的过滤器初始化。
+// 本地 Bloom 的位数与哈希个数必须与当前过滤器一致。
+func (b *RedisBloom) Load(ctx context.Context, local *Bloom) error {
+	if m, k := local.Cap(); m != b.m || k != b.k {
+		return ErrInvalidData
+	}
+	local.mu.RLock()
+	// Redis 位图按字节从高位到低位编号，本地按 uint64 从低位编号，这里逐位转换
+	buf := make([]byte, (b.m+7)/8)
+	for pos := uint64(0); pos < b.m; pos++ {
+		if local.bits[pos/64]&(1<<(pos%64)) != 0 {
+			buf[pos/8] |= 0x80 >> (pos % 8)
+		}
+	}
+	local.mu.RUnlock()
+	return b.rdb.Set(ctx, b.key, buf, 0).Err()
+}
+
+// Clear 删除 Redis 中的位图
+func (b *RedisBloom) Clear(ctx context.Context) error {
+	return b.rdb.Del(ctx, b.key).Err()
+}
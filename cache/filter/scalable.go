@@ -0,0 +1,163 @@
+package filter
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+// 可扩容布隆过滤器的参数
+const (
+	scalableGrowth     = 2   // 每个新过滤器的容量是上一个的倍数
+	scalableTightening = 0.8 // 每个新过滤器的误判率是上一个的倍数，保证总误判率收敛
+)
+
+// ScalableBloom 可扩容布隆过滤器（Scalable Bloom Filters, Almeida 等）：
+// 当前过滤器写满后追加一个容量更大、误判率更低的新过滤器，
+// 总误判率不超过 fpRate，适合事先无法预估元素数量的场景。
+type ScalableBloom struct {
+	mu      sync.RWMutex
+	filters []*Bloom
+	caps    []uint64 // 每个过滤器的设计容量
+	n       uint64   // 初始容量
+	p       float64  // 目标总误判率
+}
+
+// NewScalableBloom 创建可扩容布隆过滤器，initial 为第一个过滤器的容量
+func NewScalableBloom(initial uint64, fpRate float64) *ScalableBloom {
+	if initial == 0 {
+		initial = 1024
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+	s := &ScalableBloom{n: initial, p: fpRate}
+	s.grow()
+	return s
+}
+
+// grow 追加一个新的过滤器，调用方需持有写锁
+func (s *ScalableBloom) grow() {
+	capacity, p := s.layer(len(s.filters))
+	s.filters = append(s.filters, NewBloom(capacity, p))
+	s.caps = append(s.caps, capacity)
+}
+
+// layer 第 i 个过滤器的设计容量与误判率
+func (s *ScalableBloom) layer(i int) (uint64, float64) {
+	capacity := s.n * uint64(math.Pow(scalableGrowth, float64(i)))
+	// 第 i 个过滤器误判率 p0 * r^i，其中 p0 = p*(1-r)，几何级数之和不超过 p
+	p := s.p * (1 - scalableTightening) * math.Pow(scalableTightening, float64(i))
+	return capacity, p
+}
+
+// Add 添加元素；已经可能存在的元素不会重复写入，避免无谓地占用容量
+func (s *ScalableBloom) Add(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.testLocked(data) {
+		return
+	}
+	last := len(s.filters) - 1
+	if s.filters[last].Count() >= s.caps[last] {
+		s.grow()
+		last++
+	}
+	s.filters[last].Add(data)
+}
+
+// Test 元素可能存在时返回 true；返回 false 表示一定不存在
+func (s *ScalableBloom) Test(data []byte) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.testLocked(data)
+}
+
+func (s *ScalableBloom) testLocked(data []byte) bool {
+	for i := len(s.filters) - 1; i >= 0; i-- {
+		if s.filters[i].Test(data) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count 已添加的元素个数
+func (s *ScalableBloom) Count() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var n uint64
+	for _, f := range s.filters {
+		n += f.Count()
+	}
+	return n
+}
+
+// Filters 当前内部过滤器的个数
+func (s *ScalableBloom) Filters() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.filters)
+}
+
+// MarshalBinary 实现 encoding.BinaryMarshaler
+// 格式：magic(1) version(1) initial(8) fpRate(8) count(4) 然后依次是每个 Bloom
+func (s *ScalableBloom) MarshalBinary() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	buf := []byte{magicScalable, formatVersion}
+	buf = binary.BigEndian.AppendUint64(buf, s.n)
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(s.p))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s.filters)))
+	for _, f := range s.filters {
+		f.mu.RLock()
+		buf = f.appendBinary(buf)
+		f.mu.RUnlock()
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler
+func (s *ScalableBloom) UnmarshalBinary(data []byte) error {
+	const header = 2 + 8 + 8 + 4
+	if len(data) < header || data[0] != magicScalable || data[1] != formatVersion {
+		return ErrInvalidData
+	}
+	n := binary.BigEndian.Uint64(data[2:])
+	p := math.Float64frombits(binary.BigEndian.Uint64(data[10:]))
+	count := binary.BigEndian.Uint32(data[18:])
+	if n == 0 || count == 0 || p <= 0 || p >= 1 {
+		return ErrInvalidData
+	}
+
+	ns := &ScalableBloom{n: n, p: p}
+	rest := data[header:]
+	for i := 0; i < int(count); i++ {
+		// 设计容量按公式计算（scalableGrowth 为 2，即 n<<i），不预先分配；容量溢出说明 initial 或者层数是伪造的
+		if bits.Len64(n)+i > 64 {
+			return ErrInvalidData
+		}
+		capacity, lp := ns.layer(i)
+		b, r, err := decodeBloom(rest)
+		if err != nil {
+			return err
+		}
+		// 每一层的位数和哈希个数必须与按 initial、fpRate 推算的一致
+		if m, k := estimateParameters(capacity, lp); b.m != m || b.k != k {
+			return ErrInvalidData
+		}
+		ns.filters = append(ns.filters, b)
+		ns.caps = append(ns.caps, capacity)
+		rest = r
+	}
+	if len(rest) != 0 {
+		return ErrInvalidData
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filters, s.caps, s.n, s.p = ns.filters, ns.caps, ns.n, ns.p
+	return nil
+}
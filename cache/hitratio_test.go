@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// 命中率对比用的合成访问轨迹，种子固定，保证每次运行结果一致
+
+// zipfTrace 典型的长尾热点访问
+func zipfTrace(n int, keys uint64, seed int64) []uint64 {
+	r := rand.New(rand.NewSource(seed))
+	z := rand.NewZipf(r, 1.1, 1, keys-1)
+	trace := make([]uint64, n)
+	for i := range trace {
+		trace[i] = z.Uint64()
+	}
+	return trace
+}
+
+// scanTrace 在热点访问中穿插大段一次性顺序扫描，模拟列表接口的翻页流量
+func scanTrace(n int, keys uint64, seed int64) []uint64 {
+	const (
+		scanEvery = 2000 // 每隔多少次热点访问出现一次扫描
+		scanLen   = 3000 // 每次扫描的 key 数量
+	)
+	r := rand.New(rand.NewSource(seed))
+	z := rand.NewZipf(r, 1.1, 1, keys-1)
+	next := keys // 扫描使用热点区之外、从不重复的 key
+	trace := make([]uint64, 0, n)
+	for len(trace) < n {
+		for i := 0; i < scanEvery && len(trace) < n; i++ {
+			trace = append(trace, z.Uint64())
+		}
+		for i := 0; i < scanLen && len(trace) < n; i++ {
+			trace = append(trace, next)
+			next++
+		}
+	}
+	return trace
+}
+
+// loopTrace 循环访问一个略大于缓存容量的 key 集合，是 LRU 的最坏情况
+func loopTrace(n int, keys uint64) []uint64 {
+	trace := make([]uint64, n)
+	for i := range trace {
+		trace[i] = uint64(i) % keys
+	}
+	return trace
+}
+
+// hitRatio 回放轨迹：未命中时写入缓存，返回命中率
+func hitRatio(p Policy, capacity int, trace []uint64) float64 {
+	c := New[uint64, struct{}](WithShards(1), WithMaxEntries(capacity), WithPolicy(p))
+	hits := 0
+	for _, k := range trace {
+		if _, ok := c.Get(k); ok {
+			hits++
+			continue
+		}
+		c.Set(k, struct{}{})
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+type namedTrace struct {
+	name  string
+	trace []uint64
+}
+
+func traces() []namedTrace {
+	return []namedTrace{
+		{"zipf", zipfTrace(200_000, 50_000, 1)},
+		{"scan", scanTrace(200_000, 50_000, 2)},
+		{"loop", loopTrace(200_000, 1200)},
+	}
+}
+
+// 在带扫描的轨迹上，TinyLFU 应明显优于 LRU
+func TestTinyLFUScanResistance(t *testing.T) {
+	if testing.Short() {
+		t.Skip("trace replay is slow")
+	}
+	const capacity = 1000
+
+	for _, tr := range traces() {
+		lru := hitRatio(LRU, capacity, tr.trace)
+		tiny := hitRatio(TinyLFU, capacity, tr.trace)
+		t.Logf("%-5s lru=%.4f tinylfu=%.4f", tr.name, lru, tiny)
+		if tiny < lru {
+			t.Errorf("%s: tinylfu hit ratio %.4f should not be below lru %.4f", tr.name, tiny, lru)
+		}
+	}
+
+	scan := traces()[1].trace
+	if lru, tiny := hitRatio(LRU, capacity, scan), hitRatio(TinyLFU, capacity, scan); tiny < lru*1.1 {
+		t.Fatalf("scan: tinylfu %.4f should beat lru %.4f by at least 10%%", tiny, lru)
+	}
+}
+
+// 测试 TinyLFU 在淘汰、删除后内部链表与 map 保持一致
+func TestTinyLFUConsistency(t *testing.T) {
+	c := New[int, int](WithShards(1), WithMaxEntries(50), WithPolicy(TinyLFU))
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 20_000; i++ {
+		k := r.Intn(200)
+		switch r.Intn(4) {
+		case 0:
+			c.Delete(k)
+		case 1:
+			c.Set(k, i)
+		default:
+			c.Get(k)
+		}
+	}
+
+	s := c.shards[0]
+	p := s.policy.(*tinyLFUPolicy[int])
+	if got := p.window.Len() + p.probation.Len() + p.protected.Len(); got != len(s.items) {
+		t.Fatalf("policy tracks %d keys, shard holds %d", got, len(s.items))
+	}
+	if len(s.items) > 50 {
+		t.Fatalf("shard should be bounded, got %d", len(s.items))
+	}
+}
+
+// BenchmarkHitRatio 回放各轨迹并通过 b.ReportMetric 输出命中率：
+//
+//	go test -run=^$ -bench=HitRatio ./cache
+func BenchmarkHitRatio(b *testing.B) {
+	const capacity = 1000
+	for _, tr := range traces() {
+		for _, p := range []Policy{LRU, LFU, FIFO, TinyLFU} {
+			b.Run(fmt.Sprintf("%s/%s", tr.name, p), func(b *testing.B) {
+				var ratio float64
+				for i := 0; i < b.N; i++ {
+					ratio = hitRatio(p, capacity, tr.trace)
+				}
+				b.ReportMetric(ratio*100, "hit%")
+			})
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nuyoahch/gopulse/concurrency/singleflight"
+)
+
+// ErrNotFound 表示 key 对应的数据不存在；Loader 可以返回它，结果同样会被负缓存
+var ErrNotFound = errors.New("cache: key not found")
+
+// Loader 在缓存未命中时加载 key 对应的值
+type Loader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// BatchLoader 批量加载多个 key；返回结果里缺失的 key 视为不存在
+type BatchLoader[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// loaded 一个加载结果以及它的时间信息
+type loaded[V any] struct {
+	value    V
+	loadedAt time.Time
+	expireAt time.Time     // 逻辑过期时间，零值表示永不过期
+	delta    time.Duration // 本次加载的耗时，XFetch 用它估计重算成本
+}
+
+// expired 值在 now 时刻是否已逻辑过期（只有开启 StaleIfError 时才可能读到这样的值）
+func (l *loaded[V]) expired(now time.Time) bool {
+	return !l.expireAt.IsZero() && !now.Before(l.expireAt)
+}
+
+// LoadingCache 在未命中时自动调用 Loader 加载数据：
+//   - 同一 key 的并发未命中通过 singleflight 合并为一次加载；
+//   - 配置 RefreshAfterWrite 后，过旧的值会继续返回，同时在后台只发起一次重新加载；
+//   - 配置 EarlyRefreshBeta 后，按 XFetch 算法在过期前概率性地提前后台刷新；
+//   - 配置 StaleIfError 后，过期值重新加载失败时继续返回旧值；
+//   - 配置 NegativeTTL 后，加载错误会被缓存一段时间，避免故障时反复打到下游；
+//   - 配置 WithGuard 后，一定不存在的 key 直接返回 ErrNotFound，防止缓存穿透。
+type LoadingCache[K comparable, V any] struct {
+	cache *Cache[K, *loaded[V]]
+	errs  *Cache[K, error] // 负缓存：key -> 最近一次加载的错误
+
+	loader Loader[K, V]
+	batch  BatchLoader[K, V]
+	guard  func(K) bool
+	group  singleflight.Group[K, V]
+
+	ttl          time.Duration
+	refreshAfter time.Duration
+	negativeTTL  time.Duration
+	beta         float64
+	staleIfError time.Duration
+
+	refreshing sync.Map // 正在后台刷新的 key
+
+	// 命中与未命中由 LoadingCache 自己统计：底层缓存还会被用来读取旧值，计数不准确
+	hits, misses atomic.Uint64
+	loads        loadStats
+}
+
+// NewLoadingCache 创建一个自动加载的缓存，opts 同时作用于底层的 Cache
+func NewLoadingCache[K comparable, V any](loader Loader[K, V], opts ...Option) *LoadingCache[K, V] {
+	cfg := DefaultOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+
+	c := &LoadingCache[K, V]{
+		loader:       loader,
+		ttl:          cfg.TTL,
+		refreshAfter: cfg.RefreshAfterWrite,
+		negativeTTL:  cfg.NegativeTTL,
+		beta:         cfg.EarlyRefreshBeta,
+		staleIfError: cfg.StaleIfError,
+	}
+	if cfg.batch != nil {
+		fn, ok := cfg.batch.(BatchLoader[K, V])
+		if !ok {
+			panic(fmt.Sprintf("cache: WithBatchLoader got %T, which does not match the cache key/value types", cfg.batch))
+		}
+		c.batch = fn
+	}
+	if cfg.guard != nil {
+		fn, ok := cfg.guard.(func(K) bool)
+		if !ok {
+			panic(fmt.Sprintf("cache: WithGuard got %T, which does not match the cache key type", cfg.guard))
+		}
+		c.guard = fn
+	}
+
+	adapt := func(o *Options) {
+		// 底层缓存存的是 *loaded[V]，这里把 WithCost 的 func(K, V) 适配过去
+		if o.cost != nil {
+			fn, ok := o.cost.(func(K, V) int64)
+			if !ok {
+				panic(fmt.Sprintf("cache: WithCost got %T, which does not match the cache key/value types", o.cost))
+			}
+			o.cost = func(k K, l *loaded[V]) int64 { return fn(k, l.value) }
+		}
+		o.onEvict = unwrapCallback[K, V]("WithOnEvict", o.onEvict)
+		o.onExpire = unwrapCallback[K, V]("WithOnExpire", o.onExpire)
+		// 开启 StaleIfError 时，底层条目要比逻辑过期时间多保留一段
+		if o.TTL > 0 && o.StaleIfError > 0 {
+			o.TTL += o.StaleIfError
+		}
+	}
+	c.cache = New[K, *loaded[V]](append(opts, adapt)...)
+	c.errs = New[K, error](WithMaxEntries(cfg.MaxEntries), WithTTL(cfg.NegativeTTL))
+	return c
+}
+
+// Get 读取 key，未命中时调用 Loader 加载
+func (c *LoadingCache[K, V]) Get(ctx context.Context, key K) (V, error) {
+	var zero V
+
+	l, ok := c.cache.Get(key)
+	if ok && !l.expired(time.Now()) {
+		c.hits.Add(1)
+		c.maybeRefresh(key, l)
+		return l.value, nil
+	}
+	if !ok {
+		if err, ok := c.errs.Get(key); ok {
+			c.hits.Add(1)
+			return zero, err
+		}
+		if c.guard != nil && !c.guard(key) {
+			c.misses.Add(1)
+			return zero, ErrNotFound
+		}
+	}
+	c.misses.Add(1)
+
+	v, err := c.loadShared(ctx, key)
+	if err != nil {
+		// 逻辑上已过期但仍在 StaleIfError 窗口内的旧值
+		if l != nil && c.staleIfError > 0 && !errors.Is(err, ErrNotFound) {
+			return l.value, nil
+		}
+		return zero, err
+	}
+	return v, nil
+}
+
+// GetAll 批量读取；配置了 BatchLoader 时所有未命中的 key 通过一次调用加载，
+// 否则逐个走 Get。不存在的 key 不会出现在返回结果中。
+func (c *LoadingCache[K, V]) GetAll(ctx context.Context, keys []K) (map[K]V, error) {
+	res := make(map[K]V, len(keys))
+	stale := make(map[K]V)
+	var missing []K
+	seen := make(map[K]struct{}, len(keys))
+	now := time.Now()
+	for _, k := range keys {
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+
+		if l, ok := c.cache.Get(k); ok {
+			if !l.expired(now) {
+				c.hits.Add(1)
+				c.maybeRefresh(k, l)
+				res[k] = l.value
+				continue
+			}
+			stale[k] = l.value
+		} else if err, ok := c.errs.Get(k); ok {
+			c.hits.Add(1)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return res, err
+		} else if c.guard != nil && !c.guard(k) {
+			c.misses.Add(1)
+			continue
+		}
+		missing = append(missing, k)
+	}
+	if len(missing) == 0 {
+		return res, nil
+	}
+
+	if c.batch == nil {
+		for _, k := range missing {
+			// 逐个 Get 时由 Get 自己统计命中与未命中
+			v, err := c.Get(ctx, k)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return res, err
+			}
+			res[k] = v
+		}
+		return res, nil
+	}
+
+	c.misses.Add(uint64(len(missing)))
+	start := time.Now()
+	values, err := c.batch(ctx, missing)
+	c.loads.record(time.Since(start), err)
+	if err != nil {
+		if len(stale) == 0 {
+			return res, err
+		}
+		// 批量加载失败：有旧值的 key 继续返回旧值
+		for _, k := range missing {
+			v, ok := stale[k]
+			if !ok {
+				return res, err
+			}
+			res[k] = v
+		}
+		return res, nil
+	}
+	delta := time.Since(start)
+	for _, k := range missing {
+		v, ok := values[k]
+		if !ok {
+			c.cache.Delete(k)
+			c.rememberError(k, ErrNotFound)
+			continue
+		}
+		c.store(k, v, delta)
+		res[k] = v
+	}
+	return res, nil
+}
+
+// Set 手动写入一个值，同时清除该 key 的负缓存
+func (c *LoadingCache[K, V]) Set(key K, value V) {
+	c.store(key, value, 0)
+}
+
+// Invalidate 删除 key 的缓存值与负缓存，下次读取会重新加载
+func (c *LoadingCache[K, V]) Invalidate(key K) {
+	c.cache.Delete(key)
+	c.errs.Delete(key)
+}
+
+// Len 返回缓存的条目数（不含负缓存）
+func (c *LoadingCache[K, V]) Len() int {
+	return c.cache.Len()
+}
+
+// Stats 返回统计信息：命中指直接从缓存（包括负缓存）返回结果，Loads 包括后台刷新与批量加载
+func (c *LoadingCache[K, V]) Stats() Stats {
+	st := c.loads.fill(c.cache.Stats())
+	st.Hits, st.Misses = c.hits.Load(), c.misses.Load()
+	return st
+}
+
+// Save 把缓存值（不含负缓存）以 gob 格式写入 w，见 Cache.Save
+func (c *LoadingCache[K, V]) Save(w io.Writer) error {
+	return writeSnapshot(w, c.cache, func(l *loaded[V]) snapshotLoaded[V] {
+		return snapshotLoaded[V]{Value: l.value, LoadedAt: l.loadedAt, ExpireAt: l.expireAt, Delta: l.delta}
+	})
+}
+
+// Load 从 r 恢复 Save 写入的快照，见 Cache.Load
+func (c *LoadingCache[K, V]) Load(r io.Reader) (int, error) {
+	return readSnapshot(r, c.cache, func(l snapshotLoaded[V]) *loaded[V] {
+		return &loaded[V]{value: l.Value, loadedAt: l.LoadedAt, expireAt: l.ExpireAt, delta: l.Delta}
+	})
+}
+
+// SaveFile 把快照原子地保存到文件，见 Cache.SaveFile
+func (c *LoadingCache[K, V]) SaveFile(path string) error {
+	return saveFile(path, c.Save)
+}
+
+// LoadFile 从文件恢复快照，文件不存在时返回 0, nil
+func (c *LoadingCache[K, V]) LoadFile(path string) (int, error) {
+	return loadFile(path, c.Load)
+}
+
+// snapshotLoaded loaded 在快照中的形式，保留刷新与 XFetch 需要的时间信息
+type snapshotLoaded[V any] struct {
+	Value    V
+	LoadedAt time.Time
+	ExpireAt time.Time
+	Delta    time.Duration
+}
+
+// unwrapCallback 把 func(K, V) 回调适配为底层缓存使用的 func(K, *loaded[V])
+func unwrapCallback[K comparable, V any](name string, fn any) any {
+	cb := callback[K, V](name, fn)
+	if cb == nil {
+		return nil
+	}
+	return func(k K, l *loaded[V]) { cb(k, l.value) }
+}
+
+// Close 停止底层缓存的后台清理协程
+func (c *LoadingCache[K, V]) Close() {
+	c.cache.Close()
+	c.errs.Close()
+}
+
+// loadShared 通过 singleflight 调用 Loader，同一 key 的并发加载只执行一次。
+// Loader 的 ctx 只有在所有等待者都放弃后才会取消，单个调用方超时不会中断其他人共享的加载
+func (c *LoadingCache[K, V]) loadShared(ctx context.Context, key K) (V, error) {
+	v, err, _ := c.group.DoContextShared(ctx, key, func(ctx context.Context) (V, error) {
+		return c.load(ctx, key)
+	})
+	return v, err
+}
+
+// load 调用 Loader 并写入缓存或负缓存，只会在 singleflight 的 leader 中执行
+func (c *LoadingCache[K, V]) load(ctx context.Context, key K) (V, error) {
+	start := time.Now()
+	v, err := c.loader(ctx, key)
+	c.loads.record(time.Since(start), err)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// 数据确实不存在了，旧值也不能再用
+			c.cache.Delete(key)
+			c.rememberError(key, err)
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			// 调用方自己取消导致的错误不代表下游状态，不做负缓存
+		case !c.hasStale(key):
+			// 还有旧值可用时不做负缓存，让后续读取继续拿到旧值
+			c.rememberError(key, err)
+		}
+		var zero V
+		return zero, err
+	}
+	c.store(key, v, time.Since(start))
+	return v, nil
+}
+
+// store 写入缓存，并清除负缓存
+func (c *LoadingCache[K, V]) store(key K, value V, delta time.Duration) {
+	now := time.Now()
+	l := &loaded[V]{value: value, loadedAt: now, delta: delta}
+	if c.ttl > 0 {
+		l.expireAt = now.Add(c.ttl)
+	}
+	c.cache.Set(key, l)
+	c.errs.Delete(key)
+}
+
+// hasStale 是否还保留着 key 的（可能已逻辑过期的）旧值
+func (c *LoadingCache[K, V]) hasStale(key K) bool {
+	if c.staleIfError <= 0 {
+		return false
+	}
+	_, ok := c.cache.Get(key)
+	return ok
+}
+
+// maybeRefresh 满足 RefreshAfterWrite 或 XFetch 条件时在后台刷新
+func (c *LoadingCache[K, V]) maybeRefresh(key K, l *loaded[V]) {
+	now := time.Now()
+	due := c.refreshAfter > 0 && now.Sub(l.loadedAt) >= c.refreshAfter
+	if !due && !shouldRecomputeEarly(now, l.expireAt, l.delta, c.beta) {
+		return
+	}
+	c.refresh(key)
+}
+
+// refresh 在后台重新加载 key，同一 key 同时只有一个刷新
+func (c *LoadingCache[K, V]) refresh(key K) {
+	if _, running := c.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer c.refreshing.Delete(key)
+		start := time.Now()
+		defer func() {
+			// Loader panic 时 Do 会重新 panic，后台刷新不能让进程崩溃：记为一次失败的加载，保留旧值
+			if r := recover(); r != nil {
+				c.loads.record(time.Since(start), fmt.Errorf("cache: loader panicked during refresh: %v", r))
+			}
+		}()
+		// 刷新失败时保留旧值继续服务，不写负缓存
+		c.group.Do(key, func() (V, error) {
+			start := time.Now()
+			v, err := c.loader(context.Background(), key)
+			c.loads.record(time.Since(start), err)
+			if err != nil {
+				return v, err
+			}
+			c.store(key, v, time.Since(start))
+			return v, nil
+		})
+	}()
+}
+
+// rememberError 按 NegativeTTL 缓存加载错误
+func (c *LoadingCache[K, V]) rememberError(key K, err error) {
+	if c.negativeTTL > 0 {
+		c.errs.Set(key, err)
+	}
+}
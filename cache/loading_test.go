@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 并发未命中只调用一次 Loader
+func TestLoadingCacheDeduplicatesMisses(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	c := NewLoadingCache(func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "v:" + key, nil
+	})
+
+	const N = 10
+	var wg sync.WaitGroup
+	wg.Add(N)
+	for i := 0; i < N; i++ {
+		go func() {
+			defer wg.Done()
+			v, err := c.Get(context.Background(), "k")
+			if err != nil || v != "v:k" {
+				t.Errorf("unexpected result: %v %v", v, err)
+			}
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("loader should be called once, got %d", got)
+	}
+	// 之后的读取直接命中缓存
+	if v, _ := c.Get(context.Background(), "k"); v != "v:k" {
+		t.Fatalf("unexpected cached value: %v", v)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("cached get should not call loader, got %d", got)
+	}
+}
+
+// 测试负缓存：错误在 NegativeTTL 内直接返回
+func TestLoadingCacheNegativeTTL(t *testing.T) {
+	var calls int32
+	boom := errors.New("boom")
+	c := NewLoadingCache(func(ctx context.Context, key int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, boom
+	}, WithNegativeTTL(30*time.Millisecond))
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(context.Background(), 1); !errors.Is(err, boom) {
+			t.Fatalf("expected boom, got %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("error should be cached, loader called %d times", got)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	c.Get(context.Background(), 1)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expired negative entry should reload, loader called %d times", got)
+	}
+}
+
+// 不配置 NegativeTTL 时错误不缓存
+func TestLoadingCacheNoNegativeCaching(t *testing.T) {
+	var calls int32
+	c := NewLoadingCache(func(ctx context.Context, key int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, ErrNotFound
+	})
+
+	c.Get(context.Background(), 1)
+	c.Get(context.Background(), 1)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("errors should not be cached, loader called %d times", got)
+	}
+}
+
+// 测试写后刷新：返回旧值，同时后台只刷新一次
+func TestLoadingCacheRefreshAfterWrite(t *testing.T) {
+	var version int32
+	block := make(chan struct{})
+	c := NewLoadingCache(func(ctx context.Context, key string) (int32, error) {
+		v := atomic.AddInt32(&version, 1)
+		if v > 1 {
+			<-block
+		}
+		return v, nil
+	}, WithRefreshAfterWrite(10*time.Millisecond))
+
+	if v, _ := c.Get(context.Background(), "k"); v != 1 {
+		t.Fatalf("first load should return 1, got %d", v)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// 刷新进行中，多次读取都拿到旧值，且只触发一次加载
+	for i := 0; i < 5; i++ {
+		if v, _ := c.Get(context.Background(), "k"); v != 1 {
+			t.Fatalf("stale value should be served during refresh, got %d", v)
+		}
+	}
+	close(block)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, _ := c.Get(context.Background(), "k"); v == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("background refresh did not complete")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&version); got != 2 {
+		t.Fatalf("only one refresh should run, loader called %d times", got)
+	}
+}
+
+// 测试后台刷新时 Loader panic：进程不崩溃，旧值继续服务，记为一次失败的加载
+func TestLoadingCacheRefreshPanic(t *testing.T) {
+	var version int32
+	c := NewLoadingCache(func(ctx context.Context, key string) (int32, error) {
+		if v := atomic.AddInt32(&version, 1); v > 1 {
+			panic("boom")
+		}
+		return 1, nil
+	}, WithRefreshAfterWrite(10*time.Millisecond))
+
+	c.Get(context.Background(), "k")
+	time.Sleep(20 * time.Millisecond)
+	if v, _ := c.Get(context.Background(), "k"); v != 1 {
+		t.Fatalf("stale value should be served, got %d", v)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().LoadErrors == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("panicking refresh should be recorded as a failed load")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v, _ := c.Get(context.Background(), "k"); v != 1 {
+		t.Fatalf("stale value should survive a panicking refresh, got %d", v)
+	}
+}
+
+// 测试批量加载
+func TestLoadingCacheGetAll(t *testing.T) {
+	var batches [][]int
+	var mu sync.Mutex
+	c := NewLoadingCache(func(ctx context.Context, key int) (int, error) {
+		t.Errorf("single loader should not be used when batch loader is set")
+		return 0, nil
+	}, WithNegativeTTL(time.Minute), WithBatchLoader(func(ctx context.Context, keys []int) (map[int]int, error) {
+		mu.Lock()
+		batches = append(batches, append([]int(nil), keys...))
+		mu.Unlock()
+		res := make(map[int]int)
+		for _, k := range keys {
+			if k%2 == 0 { // 奇数 key 不存在
+				res[k] = k * 10
+			}
+		}
+		return res, nil
+	}))
+	c.Set(0, 0)
+
+	got, err := c.GetAll(context.Background(), []int{0, 1, 2, 3, 4, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[2] != 20 || got[4] != 40 {
+		t.Fatalf("unexpected result: %v", got)
+	}
+	if len(batches) != 1 || len(batches[0]) != 4 {
+		t.Fatalf("missing keys should be loaded in one batch, got %v", batches)
+	}
+
+	// 再次读取：存在的命中缓存，不存在的命中负缓存
+	if _, err := c.GetAll(context.Background(), []int{1, 2, 3, 4}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches) != 1 {
+		t.Fatalf("second GetAll should not call batch loader, got %v", batches)
+	}
+	if _, err := c.Get(context.Background(), 3); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing key should be negatively cached, got %v", err)
+	}
+}
+
+// 调用方 ctx 取消时立即返回，且不会写入负缓存
+func TestLoadingCacheContextCanceled(t *testing.T) {
+	var calls int32
+	c := NewLoadingCache(func(ctx context.Context, key int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, WithNegativeTTL(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	c.Get(ctx2, 1)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("canceled load should not be negatively cached, loader called %d times", got)
+	}
+}
+
+// 测试存在性检查：一定不存在的 key 不会调用 Loader
+func TestLoadingCacheGuard(t *testing.T) {
+	var calls int32
+	exists := map[string]bool{"a": true}
+	c := NewLoadingCache(func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "v:" + key, nil
+	}, WithGuard(func(key string) bool { return exists[key] }))
+
+	if _, err := c.Get(context.Background(), "random"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("guarded key should be reported missing, got %v", err)
+	}
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != "v:a" {
+		t.Fatalf("unexpected result: %v %v", v, err)
+	}
+	got, _ := c.GetAll(context.Background(), []string{"a", "b", "c"})
+	if len(got) != 1 {
+		t.Fatalf("guarded keys should be skipped by GetAll, got %v", got)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("loader should only run for existing key, got %d", n)
+	}
+}
@@ -0,0 +1,186 @@
+package cache
+
+import "time"
+
+// Policy 淘汰策略
+type Policy int
+
+// 内置的淘汰策略
+const (
+	LRU     Policy = iota // 淘汰最久未被访问的条目
+	LFU                   // 淘汰访问频率最低的条目（频率相同时按 LRU）
+	FIFO                  // 淘汰最早写入的条目
+	TinyLFU               // W-TinyLFU：按估计的访问频率决定新条目能否挤掉旧条目，抗扫描
+)
+
+// String 返回策略名称，方便打印
+func (p Policy) String() string {
+	switch p {
+	case LRU:
+		return "lru"
+	case LFU:
+		return "lfu"
+	case FIFO:
+		return "fifo"
+	case TinyLFU:
+		return "tinylfu"
+	default:
+		return "unknown"
+	}
+}
+
+// Options 控制缓存行为
+type Options struct {
+	Shards          int           // 分片数量，会向上取整为 2 的幂（默认 16）
+	MaxEntries      int           // 条目数上限（0 表示不限制）
+	MaxCost         int64         // 总成本上限（0 表示不限制），配合 WithCost 使用
+	Policy          Policy        // 淘汰策略（默认 LRU）
+	TTL             time.Duration // Set 使用的默认过期时间（0 表示永不过期）
+	CleanupInterval time.Duration // 后台清理过期条目的间隔（0 表示只在访问时惰性清理）
+
+	// 以下只对 LoadingCache 生效
+	RefreshAfterWrite time.Duration // 写入超过该时长后，读取时返回旧值并在后台刷新（0 表示不刷新）
+	NegativeTTL       time.Duration // 加载失败的错误缓存多久（0 表示不缓存错误）
+	EarlyRefreshBeta  float64       // XFetch 提前重算系数，越大越倾向提前刷新（0 表示关闭，通常取 1）
+	StaleIfError      time.Duration // 过期后若重新加载失败，旧值最多还能继续返回多久（0 表示关闭）
+
+	// cost 保存 WithCost 传入的 func(K, V) int64，在 New 中做类型校验
+	cost any
+	// batch 保存 WithBatchLoader 传入的 BatchLoader[K, V]，在 NewLoadingCache 中做类型校验
+	batch any
+	// guard 保存 WithGuard 传入的 func(K) bool，在 NewLoadingCache 中做类型校验
+	guard any
+	// onEvict、onExpire 保存 WithOnEvict、WithOnExpire 传入的 func(K, V)，在 New 中做类型校验
+	onEvict  any
+	onExpire any
+}
+
+// 一些默认值
+const (
+	defaultShards = 16
+)
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		Shards: defaultShards,
+		Policy: LRU,
+	}
+}
+
+// Option 函数式编程
+type Option func(*Options)
+
+// WithShards 初始化 Shards
+func WithShards(n int) Option {
+	return func(o *Options) {
+		o.Shards = n
+	}
+}
+
+// WithMaxEntries 初始化 MaxEntries
+func WithMaxEntries(n int) Option {
+	return func(o *Options) {
+		o.MaxEntries = n
+	}
+}
+
+// WithMaxCost 初始化 MaxCost
+func WithMaxCost(c int64) Option {
+	return func(o *Options) {
+		o.MaxCost = c
+	}
+}
+
+// WithPolicy 初始化 Policy
+func WithPolicy(p Policy) Option {
+	return func(o *Options) {
+		o.Policy = p
+	}
+}
+
+// WithTTL 初始化默认 TTL
+func WithTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = ttl
+	}
+}
+
+// WithCleanupInterval 初始化 CleanupInterval
+func WithCleanupInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.CleanupInterval = d
+	}
+}
+
+// WithCost 设置条目成本的计算函数，K/V 必须与 New 的类型参数一致。
+// 未设置时每个条目的成本为 1。
+func WithCost[K comparable, V any](fn func(key K, value V) int64) Option {
+	return func(o *Options) {
+		o.cost = fn
+	}
+}
+
+// WithRefreshAfterWrite 初始化 RefreshAfterWrite
+func WithRefreshAfterWrite(d time.Duration) Option {
+	return func(o *Options) {
+		o.RefreshAfterWrite = d
+	}
+}
+
+// WithNegativeTTL 初始化 NegativeTTL
+func WithNegativeTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.NegativeTTL = d
+	}
+}
+
+// WithEarlyRefresh 开启 XFetch 概率提前刷新，beta 通常取 1
+func WithEarlyRefresh(beta float64) Option {
+	return func(o *Options) {
+		o.EarlyRefreshBeta = beta
+	}
+}
+
+// WithStaleIfError 初始化 StaleIfError
+func WithStaleIfError(d time.Duration) Option {
+	return func(o *Options) {
+		o.StaleIfError = d
+	}
+}
+
+// WithBatchLoader 设置 LoadingCache.GetAll 使用的批量加载函数，K/V 必须与 NewLoadingCache 的类型参数一致
+func WithBatchLoader[K comparable, V any](fn BatchLoader[K, V]) Option {
+	return func(o *Options) {
+		o.batch = fn
+	}
+}
+
+// WithGuard 设置加载前的存在性检查，fn 返回 false 表示 key 一定不存在，
+// LoadingCache 会直接返回 ErrNotFound 而不调用 Loader。通常配合 cache/filter 使用：
+//
+//	bf := filter.NewBloom(1_000_000, 0.001)
+//	c := cache.NewLoadingCache(load, cache.WithGuard(func(id string) bool {
+//		return bf.Test([]byte(id))
+//	}))
+func WithGuard[K comparable](fn func(key K) bool) Option {
+	return func(o *Options) {
+		o.guard = fn
+	}
+}
+
+// WithOnEvict 设置条目因容量不足被淘汰时的回调，K/V 必须与 New 的类型参数一致。
+// 回调在释放分片锁之后同步执行，可以在其中访问缓存，但不宜做耗时操作。
+func WithOnEvict[K comparable, V any](fn func(key K, value V)) Option {
+	return func(o *Options) {
+		o.onEvict = fn
+	}
+}
+
+// WithOnExpire 设置过期条目被清理时的回调（读取时惰性清理或后台定期清理），
+// K/V 必须与 New 的类型参数一致，执行方式同 WithOnEvict。
+func WithOnExpire[K comparable, V any](fn func(key K, value V)) Option {
+	return func(o *Options) {
+		o.onExpire = fn
+	}
+}
@@ -0,0 +1,299 @@
+// Package peer 在 cache 之上实现 groupcache 风格的分布式对等缓存：
+// key 通过一致性哈希归属到某个节点，只有归属节点会调用 Getter 加载数据，
+// 其他节点通过 HTTP 向归属节点获取，并把热点 key 镜像到本地。
+package peer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nuyoahch/gopulse/cache"
+	"github.com/Nuyoahch/gopulse/concurrency/singleflight"
+)
+
+// Getter 在归属节点上加载 key 对应的数据；数据不存在时应返回 cache.ErrNotFound
+type Getter func(ctx context.Context, key string) ([]byte, error)
+
+// Options 控制 Group 行为
+type Options struct {
+	CacheBytes    int64         // 归属于本节点的数据缓存上限（字节）
+	HotCacheBytes int64         // 热点镜像缓存上限（字节）
+	CacheTTL      time.Duration // 本地数据的过期时间（0 表示永不过期）
+	HotTTL        time.Duration // 热点镜像的过期时间，决定其他节点更新后镜像的最长滞后
+	HotThreshold  int           // 连续从远端获取的次数达到该值即视为热点（0 表示不镜像）
+	HotWindow     time.Duration // 两次远端获取间隔超过该时长时重新计数
+	Peers         PeerPicker    // 远端节点选择器（nil 表示单机）
+
+	OnEvict  func(key string, value []byte) // 本地数据或热点镜像因容量不足被淘汰时的回调
+	OnExpire func(key string, value []byte) // 本地数据或热点镜像过期被清理时的回调
+}
+
+// 一些默认值
+const (
+	defaultCacheBytes    = 64 << 20
+	defaultHotCacheBytes = 8 << 20
+	defaultHotTTL        = 10 * time.Second
+	defaultHotThreshold  = 10
+	defaultHotWindow     = time.Second
+)
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		CacheBytes:    defaultCacheBytes,
+		HotCacheBytes: defaultHotCacheBytes,
+		HotTTL:        defaultHotTTL,
+		HotThreshold:  defaultHotThreshold,
+		HotWindow:     defaultHotWindow,
+	}
+}
+
+// Option 函数式编程
+type Option func(*Options)
+
+// WithCacheBytes 初始化 CacheBytes
+func WithCacheBytes(n int64) Option {
+	return func(o *Options) {
+		o.CacheBytes = n
+	}
+}
+
+// WithHotCacheBytes 初始化 HotCacheBytes
+func WithHotCacheBytes(n int64) Option {
+	return func(o *Options) {
+		o.HotCacheBytes = n
+	}
+}
+
+// WithCacheTTL 初始化 CacheTTL
+func WithCacheTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.CacheTTL = d
+	}
+}
+
+// WithHotTTL 初始化 HotTTL
+func WithHotTTL(d time.Duration) Option {
+	return func(o *Options) {
+		o.HotTTL = d
+	}
+}
+
+// WithHotThreshold 初始化 HotThreshold 与 HotWindow
+func WithHotThreshold(n int, window time.Duration) Option {
+	return func(o *Options) {
+		o.HotThreshold = n
+		o.HotWindow = window
+	}
+}
+
+// WithPeers 初始化 Peers
+func WithPeers(p PeerPicker) Option {
+	return func(o *Options) {
+		o.Peers = p
+	}
+}
+
+// WithOnEvict 初始化 OnEvict
+func WithOnEvict(fn func(key string, value []byte)) Option {
+	return func(o *Options) {
+		o.OnEvict = fn
+	}
+}
+
+// WithOnExpire 初始化 OnExpire
+func WithOnExpire(fn func(key string, value []byte)) Option {
+	return func(o *Options) {
+		o.OnExpire = fn
+	}
+}
+
+// Stats Group 的统计信息
+type Stats struct {
+	Gets           uint64        // Get 调用次数（不含其他节点的请求）
+	ServerRequests uint64        // 其他节点发来的请求数
+	PeerLoads      uint64        // 向归属节点获取的次数
+	PeerErrors     uint64        // 向归属节点获取失败的次数（不含 ErrNotFound）
+	PeerLoadTime   time.Duration // 向归属节点获取的累计耗时
+	LocalLoads     uint64        // 调用 Getter 的次数
+	LocalErrors    uint64        // Getter 失败的次数（包括 ErrNotFound）
+	LocalLoadTime  time.Duration // 调用 Getter 的累计耗时
+
+	Main cache.Stats // 本地数据缓存
+	Hot  cache.Stats // 热点镜像缓存
+}
+
+// counters Group 内部的并发安全计数
+type counters struct {
+	gets, serverRequests                atomic.Uint64
+	peerLoads, peerErrors, peerNanos    atomic.Uint64
+	localLoads, localErrors, localNanos atomic.Uint64
+}
+
+// Group 一个缓存命名空间，对应一类数据和一个 Getter
+type Group struct {
+	name   string
+	getter Getter
+	peers  PeerPicker
+	cfg    Options
+
+	main   *cache.Cache[string, []byte] // 归属于本节点（或远端失败时本地加载）的数据
+	hot    *cache.Cache[string, []byte] // 归属于其他节点的热点镜像
+	remote *cache.Cache[string, int]    // 热点统计：key 连续从远端获取的次数
+
+	flight singleflight.Group[string, []byte]
+	stats  counters
+}
+
+// NewGroup 创建 Group；需要对外提供服务时请通过 HTTPPool.NewGroup 创建
+func NewGroup(name string, getter Getter, opts ...Option) *Group {
+	// 默认配置
+	cfg := DefaultOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+
+	common := []cache.Option{
+		cache.WithCost(func(_ string, v []byte) int64 { return int64(len(v)) }),
+	}
+	if cfg.OnEvict != nil {
+		common = append(common, cache.WithOnEvict(cfg.OnEvict))
+	}
+	if cfg.OnExpire != nil {
+		common = append(common, cache.WithOnExpire(cfg.OnExpire))
+	}
+	return &Group{
+		name:   name,
+		getter: getter,
+		peers:  cfg.Peers,
+		cfg:    cfg,
+		main:   cache.New[string, []byte](append(common, cache.WithMaxCost(cfg.CacheBytes), cache.WithTTL(cfg.CacheTTL))...),
+		hot:    cache.New[string, []byte](append(common, cache.WithMaxCost(cfg.HotCacheBytes), cache.WithTTL(cfg.HotTTL))...),
+		remote: cache.New[string, int](cache.WithMaxEntries(1<<16), cache.WithTTL(cfg.HotWindow)),
+	}
+}
+
+// Name 返回 group 名称
+func (g *Group) Name() string { return g.name }
+
+// Get 读取 key：先查本地缓存与热点镜像，未命中时向归属节点获取（归属于自己则调用 Getter）。
+// 同一 key 的并发未命中只会产生一次远端请求或一次加载。返回的切片归调用方所有。
+func (g *Group) Get(ctx context.Context, key string) ([]byte, error) {
+	return g.get(ctx, key, false)
+}
+
+// get fromPeer 为 true 表示请求来自其他节点，此时只在本地加载，不再转发，避免节点视图不一致时来回转发
+func (g *Group) get(ctx context.Context, key string, fromPeer bool) ([]byte, error) {
+	if fromPeer {
+		g.stats.serverRequests.Add(1)
+	} else {
+		g.stats.gets.Add(1)
+	}
+	if v, ok := g.main.Get(key); ok {
+		return bytes.Clone(v), nil
+	}
+	if v, ok := g.hot.Get(key); ok {
+		return bytes.Clone(v), nil
+	}
+
+	// 加载使用共享的 ctx：第一个调用方取消时，其他还在等待的调用方不受影响
+	v, err, _ := g.flight.DoContextShared(ctx, key, func(ctx context.Context) ([]byte, error) {
+		// 等待期间可能已经有其他调用写入了缓存
+		if v, ok := g.main.Get(key); ok {
+			return v, nil
+		}
+		if v, ok := g.hot.Get(key); ok {
+			return v, nil
+		}
+		return g.load(ctx, key, fromPeer)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(v), nil
+}
+
+// load 向归属节点获取，或在本地调用 Getter
+func (g *Group) load(ctx context.Context, key string, fromPeer bool) ([]byte, error) {
+	if g.peers != nil && !fromPeer {
+		if p, ok := g.peers.PickPeer(key); ok {
+			start := time.Now()
+			v, err := p.Fetch(ctx, g.name, key)
+			g.stats.peerLoads.Add(1)
+			g.stats.peerNanos.Add(uint64(time.Since(start)))
+			if err != nil && !errors.Is(err, cache.ErrNotFound) {
+				g.stats.peerErrors.Add(1)
+			}
+			if err == nil {
+				g.recordRemote(key, v)
+				return v, nil
+			}
+			// 数据不存在是确定的结论，不需要本地再试；其他错误（节点宕机等）退回本地加载
+			if errors.Is(err, cache.ErrNotFound) {
+				return nil, err
+			}
+		}
+	}
+
+	start := time.Now()
+	v, err := g.getter(ctx, key)
+	g.stats.localLoads.Add(1)
+	g.stats.localNanos.Add(uint64(time.Since(start)))
+	if err != nil {
+		g.stats.localErrors.Add(1)
+		return nil, err
+	}
+	g.main.Set(key, v)
+	return v, nil
+}
+
+// recordRemote 统计远端获取次数，达到阈值的热点 key 镜像到本地
+func (g *Group) recordRemote(key string, v []byte) {
+	if g.cfg.HotThreshold <= 0 {
+		return
+	}
+	n, _ := g.remote.Get(key)
+	n++
+	if n >= g.cfg.HotThreshold {
+		g.remote.Delete(key)
+		g.hot.Set(key, v)
+		return
+	}
+	g.remote.Set(key, n)
+}
+
+// Remove 删除本节点上 key 的缓存与镜像（不会通知其他节点）
+func (g *Group) Remove(key string) {
+	g.main.Delete(key)
+	g.hot.Delete(key)
+}
+
+// Stats 返回统计信息
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:           g.stats.gets.Load(),
+		ServerRequests: g.stats.serverRequests.Load(),
+		PeerLoads:      g.stats.peerLoads.Load(),
+		PeerErrors:     g.stats.peerErrors.Load(),
+		PeerLoadTime:   time.Duration(g.stats.peerNanos.Load()),
+		LocalLoads:     g.stats.localLoads.Load(),
+		LocalErrors:    g.stats.localErrors.Load(),
+		LocalLoadTime:  time.Duration(g.stats.localNanos.Load()),
+		Main:           g.main.Stats(),
+		Hot:            g.hot.Stats(),
+	}
+}
+
+// SaveFile 把本地数据缓存保存到文件，热点镜像存活时间短，不做持久化
+func (g *Group) SaveFile(path string) error {
+	return g.main.SaveFile(path)
+}
+
+// LoadFile 从文件恢复本地数据缓存，文件不存在时返回 0, nil。
+// 重启后集群拓扑可能已经变化，恢复的数据不一定仍归属于本节点，只会在 CacheTTL 内被使用。
+func (g *Group) LoadFile(path string) (int, error) {
+	return g.main.LoadFile(path)
+}
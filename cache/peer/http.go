@@ -0,0 +1,188 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/Nuyoahch/gopulse/cache"
+)
+
+// 默认的 HTTP 路径前缀
+const defaultBasePath = "/_gopulse/"
+
+// HTTPPool 基于 HTTP 的节点池：既作为 PeerPicker 为本地 Group 选择归属节点，
+// 也作为 http.Handler 响应其他节点发来的 GET {basePath}{group}/{key} 请求。
+type HTTPPool struct {
+	self     string // 本节点地址，例如 "http://10.0.0.1:8080"
+	basePath string
+	client   *http.Client
+	replicas int
+
+	mu       sync.RWMutex
+	ring     *Ring
+	fetchers map[string]*httpFetcher
+	groups   map[string]*Group
+}
+
+// HTTPPoolOption 函数式编程
+type HTTPPoolOption func(*HTTPPool)
+
+// WithBasePath 初始化 HTTP 路径前缀
+func WithBasePath(p string) HTTPPoolOption {
+	return func(h *HTTPPool) {
+		h.basePath = p
+	}
+}
+
+// WithHTTPClient 初始化访问其他节点使用的 http.Client
+func WithHTTPClient(c *http.Client) HTTPPoolOption {
+	return func(h *HTTPPool) {
+		h.client = c
+	}
+}
+
+// WithReplicas 初始化每个节点的虚拟节点个数
+func WithReplicas(n int) HTTPPoolOption {
+	return func(h *HTTPPool) {
+		h.replicas = n
+	}
+}
+
+// NewHTTPPool 创建节点池，self 是本节点对其他节点可见的地址
+func NewHTTPPool(self string, opts ...HTTPPoolOption) *HTTPPool {
+	p := &HTTPPool{
+		self:     strings.TrimSuffix(self, "/"),
+		basePath: defaultBasePath,
+		client:   http.DefaultClient,
+		replicas: defaultReplicas,
+		groups:   make(map[string]*Group),
+	}
+	for _, fn := range opts {
+		fn(p)
+	}
+	if !strings.HasSuffix(p.basePath, "/") {
+		p.basePath += "/"
+	}
+	p.Set(p.self)
+	return p
+}
+
+// NewGroup 创建一个使用本节点池的 Group，并注册到 HTTP 服务中
+func (p *HTTPPool) NewGroup(name string, getter Getter, opts ...Option) *Group {
+	g := NewGroup(name, getter, append(opts, WithPeers(p))...)
+	p.mu.Lock()
+	p.groups[name] = g
+	p.mu.Unlock()
+	return g
+}
+
+// Set 用完整的节点列表重建哈希环；列表中不包含自己时会自动加入
+func (p *HTTPPool) Set(peers ...string) {
+	ring := NewRing(p.replicas, nil)
+	fetchers := make(map[string]*httpFetcher, len(peers))
+	for _, peer := range append(peers, p.self) {
+		peer = strings.TrimSuffix(peer, "/")
+		ring.Add(peer)
+		fetchers[peer] = &httpFetcher{baseURL: peer + p.basePath, client: p.client}
+	}
+
+	p.mu.Lock()
+	p.ring, p.fetchers = ring, fetchers
+	p.mu.Unlock()
+}
+
+// Watch 通过 Discovery 持续更新节点列表，直到 ctx 取消
+func (p *HTTPPool) Watch(ctx context.Context, d Discovery) error {
+	return d.Watch(ctx, func(peers []string) { p.Set(peers...) })
+}
+
+// PickPeer 实现 PeerPicker
+func (p *HTTPPool) PickPeer(key string) (Fetcher, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	owner := p.ring.Get(key)
+	if owner == "" || owner == p.self {
+		return nil, false
+	}
+	return p.fetchers[owner], true
+}
+
+// ServeHTTP 响应其他节点的获取请求
+func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 协议中 404 专指数据不存在，其他异常一律返回 400
+	if !strings.HasPrefix(r.URL.Path, p.basePath) {
+		http.Error(w, "unexpected path: "+r.URL.Path, http.StatusBadRequest)
+		return
+	}
+	// 路径格式：{basePath}{group}/{key}，两段都经过 PathEscape
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.EscapedPath(), p.basePath), "/", 2)
+	if len(parts) != 2 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	groupName, err1 := url.PathUnescape(parts[0])
+	key, err2 := url.PathUnescape(parts[1])
+	if err1 != nil || err2 != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.RLock()
+	g := p.groups[groupName]
+	p.mu.RUnlock()
+	if g == nil {
+		http.Error(w, "no such group: "+groupName, http.StatusBadRequest)
+		return
+	}
+
+	v, err := g.get(r.Context(), key, true)
+	if errors.Is(err, cache.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(v)
+}
+
+// httpFetcher 通过 HTTP 访问一个远端节点
+type httpFetcher struct {
+	baseURL string
+	client  *http.Client
+}
+
+// Fetch 实现 Fetcher；远端返回 404 时映射为 cache.ErrNotFound
+func (f *httpFetcher) Fetch(ctx context.Context, group, key string) ([]byte, error) {
+	u := f.baseURL + url.PathEscape(group) + "/" + url.PathEscape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, cache.ErrNotFound
+	default:
+		return nil, fmt.Errorf("peer: %s returned %s: %s", u, resp.Status, strings.TrimSpace(string(body)))
+	}
+}
@@ -0,0 +1,231 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nuyoahch/gopulse/cache"
+)
+
+// cluster 启动 n 个进程内节点，每个节点一个 HTTPPool
+func cluster(t *testing.T, n int) ([]*HTTPPool, []*httptest.Server) {
+	t.Helper()
+	pools := make([]*HTTPPool, n)
+	servers := make([]*httptest.Server, n)
+	addrs := make([]string, n)
+	for i := range servers {
+		servers[i] = httptest.NewUnstartedServer(nil)
+		addrs[i] = "http://" + servers[i].Listener.Addr().String()
+	}
+	for i, srv := range servers {
+		pools[i] = NewHTTPPool(addrs[i])
+		srv.Config.Handler = pools[i]
+		srv.Start()
+		t.Cleanup(srv.Close)
+	}
+	for _, p := range pools {
+		p.Set(addrs...)
+	}
+	return pools, servers
+}
+
+// 集群内同一个 key 的并发读取只会在归属节点加载一次
+func TestGroupLoadsOnceAcrossCluster(t *testing.T) {
+	pools, _ := cluster(t, 3)
+
+	var loads atomic.Int32
+	getter := func(_ context.Context, key string) ([]byte, error) {
+		loads.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return []byte("v-" + key), nil
+	}
+	groups := make([]*Group, len(pools))
+	for i, p := range pools {
+		groups[i] = p.NewGroup("scores", getter)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(g *Group) {
+			defer wg.Done()
+			v, err := g.Get(context.Background(), "tom")
+			if err != nil || string(v) != "v-tom" {
+				t.Errorf("Get = %q, %v", v, err)
+			}
+		}(groups[i%len(groups)])
+	}
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("getter called %d times, want 1", n)
+	}
+
+	var local, gets uint64
+	for _, g := range groups {
+		st := g.Stats()
+		local += st.LocalLoads
+		gets += st.Gets
+	}
+	if local != 1 || gets != 30 {
+		t.Fatalf("stats: local loads %d, gets %d", local, gets)
+	}
+}
+
+// 数据不存在时所有节点都返回 cache.ErrNotFound
+func TestGroupNotFound(t *testing.T) {
+	pools, _ := cluster(t, 2)
+	groups := make([]*Group, len(pools))
+	for i, p := range pools {
+		groups[i] = p.NewGroup("scores", func(context.Context, string) ([]byte, error) {
+			return nil, cache.ErrNotFound
+		})
+	}
+	for _, g := range groups {
+		if _, err := g.Get(context.Background(), "nobody"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+}
+
+// 远端频繁访问的 key 会被镜像到本地，不再请求归属节点
+func TestGroupHotKeyMirror(t *testing.T) {
+	pools, servers := cluster(t, 2)
+
+	// 统计发往每个节点的请求数
+	var hits [2]atomic.Int32
+	for i, srv := range servers {
+		h := srv.Config.Handler
+		srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i].Add(1)
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	getter := func(_ context.Context, key string) ([]byte, error) {
+		return []byte(key), nil
+	}
+	// 关闭本地数据缓存，只观察热点镜像
+	opts := []Option{WithCacheBytes(1), WithHotThreshold(3, time.Minute)}
+	g0 := pools[0].NewGroup("hot", getter, opts...)
+	pools[1].NewGroup("hot", getter, opts...)
+
+	// 找一个归属于节点 1 的 key
+	key := ""
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		if _, ok := pools[0].PickPeer(k); ok {
+			key = k
+			break
+		}
+	}
+	if key == "" {
+		t.Skip("no key owned by the remote peer")
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := g0.Get(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := hits[1].Load(); n != 3 {
+		t.Fatalf("owner received %d requests, want 3", n)
+	}
+}
+
+// 第一个调用方取消后，合并到同一次加载的其他调用方仍然拿到结果
+func TestGroupFirstCallerCancel(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	g := NewGroup("cancel", func(ctx context.Context, key string) ([]byte, error) {
+		close(started)
+		select {
+		case <-release:
+			return []byte("v-" + key), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := g.Get(ctx, "k")
+		first <- err
+	}()
+	<-started
+
+	second := make(chan []byte, 1)
+	go func() {
+		v, err := g.Get(context.Background(), "k")
+		if err != nil {
+			t.Errorf("second caller: %v", err)
+		}
+		second <- v
+	}()
+	// 等第二个调用方加入同一次加载
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller should see its own cancellation, got %v", err)
+	}
+	close(release)
+	if v := <-second; string(v) != "v-k" {
+		t.Fatalf("second caller = %q, want v-k", v)
+	}
+}
+
+// 远端节点不可用时退回本地加载
+func TestGroupPeerDown(t *testing.T) {
+	p := NewHTTPPool("http://127.0.0.1:1")
+	p.Set("http://127.0.0.1:1", "http://127.0.0.1:2")
+	var loads atomic.Int32
+	g := p.NewGroup("down", func(_ context.Context, key string) ([]byte, error) {
+		loads.Add(1)
+		return []byte(key), nil
+	})
+	for _, k := range []string{"a", "b", "c", "d"} {
+		v, err := g.Get(context.Background(), k)
+		if err != nil || string(v) != k {
+			t.Fatalf("Get(%s) = %q, %v", k, v, err)
+		}
+	}
+	if loads.Load() != 4 {
+		t.Fatalf("getter called %d times, want 4", loads.Load())
+	}
+}
+
+// Discovery 推送的节点列表会更新哈希环
+func TestHTTPPoolWatch(t *testing.T) {
+	p := NewHTTPPool("http://self")
+	if _, ok := p.PickPeer("k"); ok {
+		t.Fatal("single node should own every key")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Watch(ctx, StaticDiscovery{"http://a", "http://b", "http://c"}) }()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.mu.RLock()
+		n := len(p.ring.Nodes())
+		p.mu.RUnlock()
+		if n == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("ring has %d nodes, want 4", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Watch returned %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package peer
+
+import "context"
+
+// PeerPicker 根据 key 选出拥有它的远端节点；key 归属于自己时返回 false
+type PeerPicker interface {
+	PickPeer(key string) (Fetcher, bool)
+}
+
+// Fetcher 从远端节点获取某个 group 下 key 的数据
+type Fetcher interface {
+	Fetch(ctx context.Context, group, key string) ([]byte, error)
+}
+
+// Discovery 提供集群节点列表，可以对接注册中心（etcd、Consul、Kubernetes Endpoints 等）
+type Discovery interface {
+	// Watch 阻塞监听节点变化，每次变化以完整的节点列表调用 update，直到 ctx 取消
+	Watch(ctx context.Context, update func(peers []string)) error
+}
+
+// StaticDiscovery 固定的节点列表
+type StaticDiscovery []string
+
+// Watch 实现 Discovery：立即回调一次，然后等待 ctx 取消
+func (s StaticDiscovery) Watch(ctx context.Context, update func(peers []string)) error {
+	update(append([]string(nil), s...))
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,105 @@
+package peer
+
+import (
+	"hash/crc32"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Hash 把数据映射为环上的位置
+type Hash func(data []byte) uint32
+
+// 默认每个真实节点对应的虚拟节点个数
+const defaultReplicas = 50
+
+// Ring 带虚拟节点的一致性哈希环，并发安全。
+// 每个真实节点在环上放置 replicas 个虚拟节点，使 key 分布更均匀，
+// 节点增减时只有相邻区间的 key 会换主。
+type Ring struct {
+	mu       sync.RWMutex
+	hash     Hash
+	replicas int
+	keys     []uint32          // 已排序的虚拟节点位置
+	owners   map[uint32]string // 虚拟节点位置 -> 真实节点
+	nodes    map[string]struct{}
+}
+
+// NewRing 创建哈希环，replicas <= 0 时使用默认值，hash 为 nil 时使用 crc32
+func NewRing(replicas int, hash Hash) *Ring {
+	if replicas <= 0 {
+		replicas = defaultReplicas
+	}
+	if hash == nil {
+		hash = crc32.ChecksumIEEE
+	}
+	return &Ring{
+		hash:     hash,
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		nodes:    make(map[string]struct{}),
+	}
+}
+
+// Add 添加节点，已存在的节点会被忽略
+func (r *Ring) Add(nodes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, node := range nodes {
+		if _, ok := r.nodes[node]; ok {
+			continue
+		}
+		r.nodes[node] = struct{}{}
+		for i := 0; i < r.replicas; i++ {
+			h := r.hash([]byte(strconv.Itoa(i) + node))
+			r.keys = append(r.keys, h)
+			r.owners[h] = node
+		}
+	}
+	slices.Sort(r.keys)
+}
+
+// Remove 移除节点
+func (r *Ring) Remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.nodes[node]; !ok {
+		return
+	}
+	delete(r.nodes, node)
+	r.keys = slices.DeleteFunc(r.keys, func(h uint32) bool {
+		if r.owners[h] == node {
+			delete(r.owners, h)
+			return true
+		}
+		return false
+	})
+}
+
+// Get 返回 key 的归属节点，环为空时返回空字符串
+func (r *Ring) Get(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.keys) == 0 {
+		return ""
+	}
+	h := r.hash([]byte(key))
+	// 顺时针找到第一个不小于 h 的虚拟节点，超过末尾则回到环首
+	idx := sort.Search(len(r.keys), func(i int) bool { return r.keys[i] >= h })
+	if idx == len(r.keys) {
+		idx = 0
+	}
+	return r.owners[r.keys[idx]]
+}
+
+// Nodes 返回当前所有真实节点（无序）
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	nodes := make([]string, 0, len(r.nodes))
+	for n := range r.nodes {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
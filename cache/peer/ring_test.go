@@ -0,0 +1,77 @@
+package peer
+
+import (
+	"strconv"
+	"testing"
+)
+
+// 使用可预测的哈希函数测试环上的查找
+func TestRingGet(t *testing.T) {
+	r := NewRing(3, func(data []byte) uint32 {
+		i, _ := strconv.Atoi(string(data))
+		return uint32(i)
+	})
+	// 虚拟节点：2/12/22、4/14/24、6/16/26
+	r.Add("6", "4", "2")
+
+	cases := map[string]string{
+		"2": "2", "11": "2", "23": "4", "27": "2",
+	}
+	for k, want := range cases {
+		if got := r.Get(k); got != want {
+			t.Errorf("Get(%s) = %s, want %s", k, got, want)
+		}
+	}
+
+	// 加入节点 8 后，27 落到 28
+	r.Add("8")
+	if got := r.Get("27"); got != "8" {
+		t.Errorf("Get(27) = %s, want 8", got)
+	}
+
+	r.Remove("8")
+	if got := r.Get("27"); got != "2" {
+		t.Errorf("after remove Get(27) = %s, want 2", got)
+	}
+}
+
+// 节点变化时只有少部分 key 迁移，且分布大致均匀
+func TestRingBalanceAndStability(t *testing.T) {
+	r := NewRing(100, nil)
+	r.Add("a", "b", "c", "d")
+
+	const n = 10000
+	before := make([]string, n)
+	counts := map[string]int{}
+	for i := range before {
+		before[i] = r.Get("key-" + strconv.Itoa(i))
+		counts[before[i]]++
+	}
+	for node, c := range counts {
+		if c < n/4/2 || c > n/4*2 {
+			t.Errorf("node %s owns %d keys, distribution too skewed: %v", node, c, counts)
+		}
+	}
+
+	r.Add("e")
+	moved := 0
+	for i := range before {
+		if got := r.Get("key-" + strconv.Itoa(i)); got != before[i] {
+			if got != "e" {
+				t.Fatalf("key moved between existing nodes: %s -> %s", before[i], got)
+			}
+			moved++
+		}
+	}
+	// 理想情况下约 1/5 的 key 迁移到新节点
+	if moved < n/10 || moved > n/3 {
+		t.Fatalf("unexpected number of moved keys: %d", moved)
+	}
+}
+
+func TestRingEmpty(t *testing.T) {
+	r := NewRing(0, nil)
+	if got := r.Get("x"); got != "" {
+		t.Fatalf("empty ring should return empty owner, got %q", got)
+	}
+}
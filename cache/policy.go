@@ -0,0 +1,160 @@
+package cache
+
+import "container/list"
+
+// policy 是分片内部使用的淘汰策略；所有方法都在分片锁内调用，实现无需自行加锁
+type policy[K comparable] interface {
+	add(key K)         // 新条目写入
+	access(key K)      // 条目被读取或覆盖写
+	remove(key K)      // 条目被删除（显式删除、过期或淘汰）
+	victim() (K, bool) // 容量超限时返回下一个应淘汰的 key
+}
+
+// newPolicy 按配置创建一个策略实例，capacity 是分片的条目上限（0 表示不限制）
+func newPolicy[K comparable](p Policy, capacity int, hash func(K) uint64) policy[K] {
+	switch p {
+	case TinyLFU:
+		return newTinyLFU[K](capacity, hash)
+	case LFU:
+		return newLFU[K]()
+	case FIFO:
+		return newFIFO[K]()
+	default:
+		return newLRU[K]()
+	}
+}
+
+// ---- LRU ----
+
+// lruPolicy 最近最少使用：链表头部是最新访问的，尾部是最久未访问的
+type lruPolicy[K comparable] struct {
+	ll    *list.List
+	items map[K]*list.Element
+}
+
+func newLRU[K comparable]() *lruPolicy[K] {
+	return &lruPolicy[K]{ll: list.New(), items: make(map[K]*list.Element)}
+}
+
+func (p *lruPolicy[K]) add(key K) {
+	if el, ok := p.items[key]; ok {
+		p.ll.MoveToFront(el)
+		return
+	}
+	p.items[key] = p.ll.PushFront(key)
+}
+
+func (p *lruPolicy[K]) access(key K) {
+	if el, ok := p.items[key]; ok {
+		p.ll.MoveToFront(el)
+	}
+}
+
+func (p *lruPolicy[K]) remove(key K) {
+	if el, ok := p.items[key]; ok {
+		p.ll.Remove(el)
+		delete(p.items, key)
+	}
+}
+
+func (p *lruPolicy[K]) victim() (K, bool) {
+	el := p.ll.Back()
+	if el == nil {
+		var zero K
+		return zero, false
+	}
+	return el.Value.(K), true
+}
+
+// ---- FIFO ----
+
+// fifoPolicy 先进先出：访问不会改变顺序
+type fifoPolicy[K comparable] struct {
+	lruPolicy[K]
+}
+
+func newFIFO[K comparable]() *fifoPolicy[K] {
+	return &fifoPolicy[K]{lruPolicy: *newLRU[K]()}
+}
+
+func (p *fifoPolicy[K]) access(K) {}
+
+// ---- LFU ----
+
+// lfuBucket 同一访问频率的条目集合，内部按 LRU 排列
+type lfuBucket[K comparable] struct {
+	freq  int
+	items *list.List
+}
+
+// lfuItem 记录条目所在的频率桶以及在桶内的位置
+type lfuItem[K comparable] struct {
+	bucket *list.Element // 元素值为 *lfuBucket[K]
+	el     *list.Element // 元素值为 K
+}
+
+// lfuPolicy O(1) LFU：频率桶按 freq 升序串成链表，淘汰时取最低频率桶的尾部
+type lfuPolicy[K comparable] struct {
+	buckets *list.List
+	items   map[K]*lfuItem[K]
+}
+
+func newLFU[K comparable]() *lfuPolicy[K] {
+	return &lfuPolicy[K]{buckets: list.New(), items: make(map[K]*lfuItem[K])}
+}
+
+func (p *lfuPolicy[K]) add(key K) {
+	if _, ok := p.items[key]; ok {
+		p.access(key)
+		return
+	}
+	front := p.buckets.Front()
+	if front == nil || front.Value.(*lfuBucket[K]).freq != 1 {
+		front = p.buckets.PushFront(&lfuBucket[K]{freq: 1, items: list.New()})
+	}
+	b := front.Value.(*lfuBucket[K])
+	p.items[key] = &lfuItem[K]{bucket: front, el: b.items.PushFront(key)}
+}
+
+func (p *lfuPolicy[K]) access(key K) {
+	it, ok := p.items[key]
+	if !ok {
+		return
+	}
+	cur := it.bucket.Value.(*lfuBucket[K])
+
+	// 找到（或创建）freq+1 的桶
+	next := it.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket[K]).freq != cur.freq+1 {
+		next = p.buckets.InsertAfter(&lfuBucket[K]{freq: cur.freq + 1, items: list.New()}, it.bucket)
+	}
+
+	cur.items.Remove(it.el)
+	if cur.items.Len() == 0 {
+		p.buckets.Remove(it.bucket)
+	}
+	it.bucket = next
+	it.el = next.Value.(*lfuBucket[K]).items.PushFront(key)
+}
+
+func (p *lfuPolicy[K]) remove(key K) {
+	it, ok := p.items[key]
+	if !ok {
+		return
+	}
+	b := it.bucket.Value.(*lfuBucket[K])
+	b.items.Remove(it.el)
+	if b.items.Len() == 0 {
+		p.buckets.Remove(it.bucket)
+	}
+	delete(p.items, key)
+}
+
+func (p *lfuPolicy[K]) victim() (K, bool) {
+	front := p.buckets.Front()
+	if front == nil {
+		var zero K
+		return zero, false
+	}
+	return front.Value.(*lfuBucket[K]).items.Back().Value.(K), true
+}
@@ -0,0 +1,81 @@
+package cache
+
+import "testing"
+
+// 测试 LRU：被访问过的条目不会被淘汰
+func TestLRUEviction(t *testing.T) {
+	c := New[string, int](WithShards(1), WithMaxEntries(2), WithPolicy(LRU))
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3) // 淘汰 b
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("b should be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("a should be kept")
+	}
+}
+
+// 测试 FIFO：访问不影响淘汰顺序
+func TestFIFOEviction(t *testing.T) {
+	c := New[string, int](WithShards(1), WithMaxEntries(2), WithPolicy(FIFO))
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3) // 淘汰最早写入的 a
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("a should be evicted")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Fatalf("b should be kept")
+	}
+}
+
+// 测试 LFU：访问频率最低的条目被淘汰，同频率按 LRU
+func TestLFUEviction(t *testing.T) {
+	c := New[string, int](WithShards(1), WithMaxEntries(3), WithPolicy(LFU))
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	for i := 0; i < 3; i++ {
+		c.Get("a")
+	}
+	c.Get("b")
+	c.Get("c")
+	c.Set("d", 4) // b、c 频率相同，b 更久未访问，淘汰 b
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("b should be evicted")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if _, ok := c.Get(k); !ok {
+			t.Fatalf("%s should be kept", k)
+		}
+	}
+}
+
+// 测试 LFU 桶在删除后依然保持一致
+func TestLFURemove(t *testing.T) {
+	p := newLFU[int]()
+	p.add(1)
+	p.add(2)
+	p.access(2)
+	p.remove(1)
+
+	if k, ok := p.victim(); !ok || k != 2 {
+		t.Fatalf("unexpected victim: %v %v", k, ok)
+	}
+	p.remove(2)
+	if _, ok := p.victim(); ok {
+		t.Fatalf("empty policy should have no victim")
+	}
+	if p.buckets.Len() != 0 {
+		t.Fatalf("empty buckets should be released, got %d", p.buckets.Len())
+	}
+}
@@ -0,0 +1,226 @@
+package cache
+
+import "sync"
+
+// entry 单个缓存条目
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt int64 // 过期时间（UnixNano），0 表示永不过期
+	cost     int64
+}
+
+// expired 判断条目在 now 时刻是否已过期
+func (e *entry[K, V]) expired(now int64) bool {
+	return e.expireAt > 0 && now >= e.expireAt
+}
+
+// shard 一个分片：独立的锁、map 和淘汰策略，降低热点 key 之间的锁竞争
+type shard[K comparable, V any] struct {
+	mu        sync.Mutex
+	items     map[K]*entry[K, V]
+	policy    policy[K]
+	newPolicy func() policy[K]
+	cost      int64
+
+	maxEntries int   // 本分片的条目上限（0 表示不限制）
+	maxCost    int64 // 本分片的成本上限（0 表示不限制）
+
+	// 统计计数，受 mu 保护
+	hits, misses, evictions, expirations uint64
+
+	// 回调在释放锁之后执行，持锁期间先把被移除的条目暂存起来
+	onEvict, onExpire func(K, V)
+	evicted, expired  []*entry[K, V]
+}
+
+func newShard[K comparable, V any](newPolicy func() policy[K], maxEntries int, maxCost int64, onEvict, onExpire func(K, V)) *shard[K, V] {
+	return &shard[K, V]{
+		items:      make(map[K]*entry[K, V]),
+		policy:     newPolicy(),
+		newPolicy:  newPolicy,
+		maxEntries: maxEntries,
+		maxCost:    maxCost,
+		onEvict:    onEvict,
+		onExpire:   onExpire,
+	}
+}
+
+// unlock 释放锁，然后执行持锁期间暂存的淘汰、过期回调，避免回调里再访问缓存时死锁
+func (s *shard[K, V]) unlock() {
+	evicted, expired := s.evicted, s.expired
+	s.evicted, s.expired = nil, nil
+	s.mu.Unlock()
+
+	for _, e := range evicted {
+		s.onEvict(e.key, e.value)
+	}
+	for _, e := range expired {
+		s.onExpire(e.key, e.value)
+	}
+}
+
+// evictedLocked 记录一次淘汰，调用方需持有锁
+func (s *shard[K, V]) evictedLocked(e *entry[K, V]) {
+	s.evictions++
+	if s.onEvict != nil {
+		s.evicted = append(s.evicted, e)
+	}
+}
+
+// expiredLocked 删除一个已过期的条目并记录，调用方需持有锁
+func (s *shard[K, V]) expiredLocked(e *entry[K, V]) {
+	s.removeLocked(e)
+	s.expirations++
+	if s.onExpire != nil {
+		s.expired = append(s.expired, e)
+	}
+}
+
+// get 读取条目；已过期的条目会被顺手删除
+func (s *shard[K, V]) get(key K, now int64) (V, bool) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	e, ok := s.items[key]
+	if !ok {
+		s.misses++
+		var zero V
+		return zero, false
+	}
+	if e.expired(now) {
+		s.expiredLocked(e)
+		s.misses++
+		var zero V
+		return zero, false
+	}
+	s.hits++
+	s.policy.access(key)
+	return e.value, true
+}
+
+// set 写入条目，必要时按策略淘汰其他条目
+func (s *shard[K, V]) set(key K, value V, expireAt, cost, now int64) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if e, ok := s.items[key]; ok {
+		// 覆盖写：更新值、过期时间和成本，成本变大时可能需要淘汰
+		s.cost += cost - e.cost
+		e.value, e.expireAt, e.cost = value, expireAt, cost
+		s.policy.access(key)
+		s.evictLocked(0, 0, now)
+		return
+	}
+
+	// 单个条目就超过成本上限，直接丢弃，记为一次淘汰
+	if s.maxCost > 0 && cost > s.maxCost {
+		s.evictedLocked(&entry[K, V]{key: key, value: value, expireAt: expireAt, cost: cost})
+		return
+	}
+
+	// 新条目：先腾出空间再写入，避免刚写入的条目立刻成为淘汰对象
+	s.evictLocked(1, cost, now)
+	s.items[key] = &entry[K, V]{key: key, value: value, expireAt: expireAt, cost: cost}
+	s.cost += cost
+	s.policy.add(key)
+}
+
+// evictLocked 循环淘汰，直到再放入 n 个、总成本为 cost 的条目也不会超限，调用方需持有锁。
+// 被选中的条目如果已经过期，记为过期而不是淘汰。
+func (s *shard[K, V]) evictLocked(n int, cost, now int64) {
+	for s.overflowLocked(n, cost) {
+		k, ok := s.policy.victim()
+		if !ok {
+			return
+		}
+		e, ok := s.items[k]
+		if !ok {
+			// 策略与 map 不一致时只清理策略里的记录，防止死循环
+			s.policy.remove(k)
+			continue
+		}
+		if e.expired(now) {
+			s.expiredLocked(e)
+			continue
+		}
+		s.removeLocked(e)
+		s.evictedLocked(e)
+	}
+}
+
+// overflowLocked 再放入 n 个、总成本为 cost 的条目后是否超出上限
+func (s *shard[K, V]) overflowLocked(n int, cost int64) bool {
+	if s.maxEntries > 0 && len(s.items)+n > s.maxEntries {
+		return true
+	}
+	return s.maxCost > 0 && s.cost+cost > s.maxCost
+}
+
+// removeLocked 删除条目并同步策略状态，调用方需持有锁
+func (s *shard[K, V]) removeLocked(e *entry[K, V]) {
+	delete(s.items, e.key)
+	s.policy.remove(e.key)
+	s.cost -= e.cost
+}
+
+// delete 删除 key，返回是否真的删除了未过期的条目
+func (s *shard[K, V]) delete(key K, now int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.items[key]
+	if !ok {
+		return false
+	}
+	s.removeLocked(e)
+	return !e.expired(now)
+}
+
+// deleteExpired 清理本分片所有已过期的条目
+func (s *shard[K, V]) deleteExpired(now int64) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	for _, e := range s.items {
+		if e.expired(now) {
+			s.expiredLocked(e)
+		}
+	}
+}
+
+// len 当前条目数（包含尚未清理的过期条目）
+func (s *shard[K, V]) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// clear 清空本分片
+func (s *shard[K, V]) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[K]*entry[K, V])
+	s.policy = s.newPolicy()
+	s.cost = 0
+}
+
+// stats 读取本分片的统计计数
+func (s *shard[K, V]) stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{Hits: s.hits, Misses: s.misses, Evictions: s.evictions, Expirations: s.expirations}
+}
+
+// snapshot 复制本分片在 now 时刻未过期的条目
+func (s *shard[K, V]) snapshot(now int64) []entry[K, V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]entry[K, V], 0, len(s.items))
+	for _, e := range s.items {
+		if !e.expired(now) {
+			out = append(out, *e)
+		}
+	}
+	return out
+}
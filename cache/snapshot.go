@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrBadSnapshot 快照格式不正确或版本不兼容
+var ErrBadSnapshot = errors.New("cache: bad snapshot")
+
+// 快照文件头
+const (
+	snapshotMagic   = "gopulse-cache"
+	snapshotVersion = 1
+)
+
+// snapshotHeader 快照文件头，用于识别格式与版本
+type snapshotHeader struct {
+	Magic   string
+	Version int
+}
+
+// snapshotEntry 快照中的一个条目，ExpireAt 为绝对时间（UnixNano），0 表示永不过期。
+// 使用绝对时间意味着恢复后条目的剩余寿命与保存前一致，停机期间已到期的条目会被丢弃。
+type snapshotEntry[K comparable, T any] struct {
+	Key      K
+	Value    T
+	ExpireAt int64
+}
+
+// Save 把所有未过期的条目以 gob 格式写入 w，K 和 V 必须可以被 gob 编码。
+// 每个分片只在复制条目时短暂加锁，保存期间的写入可能只有一部分被包含。
+func (c *Cache[K, V]) Save(w io.Writer) error {
+	return writeSnapshot(w, c, func(v V) V { return v })
+}
+
+// Load 从 r 读取 Save 写入的快照并写入缓存，返回写入的条目数；已过期的条目会被跳过。
+// 已存在的 key 会被覆盖，超出容量时按淘汰策略淘汰。
+func (c *Cache[K, V]) Load(r io.Reader) (int, error) {
+	return readSnapshot(r, c, func(v V) V { return v })
+}
+
+// SaveFile 把快照保存到文件；先写临时文件再重命名，进程中途崩溃也不会留下半个快照
+func (c *Cache[K, V]) SaveFile(path string) error {
+	return saveFile(path, c.Save)
+}
+
+// LoadFile 从文件恢复快照，文件不存在时返回 0, nil，方便首次启动
+func (c *Cache[K, V]) LoadFile(path string) (int, error) {
+	return loadFile(path, c.Load)
+}
+
+// writeSnapshot 把 c 中未过期的条目经 conv 转换后写入 w
+func writeSnapshot[K comparable, V, T any](w io.Writer, c *Cache[K, V], conv func(V) T) error {
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Magic: snapshotMagic, Version: snapshotVersion}); err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	for _, s := range c.shards {
+		for _, e := range s.snapshot(now) {
+			if err := enc.Encode(snapshotEntry[K, T]{Key: e.key, Value: conv(e.value), ExpireAt: e.expireAt}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readSnapshot 读取 writeSnapshot 写入的条目，经 conv 转换后写入 c
+func readSnapshot[K comparable, V, T any](r io.Reader, c *Cache[K, V], conv func(T) V) (int, error) {
+	dec := gob.NewDecoder(bufio.NewReader(r))
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+	}
+	if h.Magic != snapshotMagic || h.Version != snapshotVersion {
+		return 0, fmt.Errorf("%w: unexpected header %q v%d", ErrBadSnapshot, h.Magic, h.Version)
+	}
+
+	n := 0
+	now := time.Now().UnixNano()
+	for {
+		var e snapshotEntry[K, T]
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if e.ExpireAt > 0 && now >= e.ExpireAt {
+			continue
+		}
+		c.set(e.Key, conv(e.Value), e.ExpireAt, now)
+		n++
+	}
+}
+
+// saveFile 通过 save 把快照原子地写入 path
+func saveFile(path string, save func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// 重命名成功后 Remove 会失败，忽略即可
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	if err := save(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// loadFile 打开 path 并通过 load 恢复快照，文件不存在时返回 0, nil
+func loadFile(path string, load func(io.Reader) (int, error)) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return load(f)
+}
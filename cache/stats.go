@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats 缓存统计，用于评估命中率、调整容量
+type Stats struct {
+	Hits        uint64        // 命中次数
+	Misses      uint64        // 未命中次数（包括读到已过期的条目）
+	Evictions   uint64        // 因容量不足被淘汰的条目数
+	Expirations uint64        // 过期后被清理的条目数
+	Loads       uint64        // 加载次数（LoadingCache 调用 Loader，TwoLevel 读取 L2）
+	LoadErrors  uint64        // 失败的加载次数（包括 ErrNotFound）
+	LoadTime    time.Duration // 加载累计耗时
+}
+
+// HitRatio 命中率，没有任何读取时返回 0
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// AverageLoadTime 平均每次加载的耗时
+func (s Stats) AverageLoadTime() time.Duration {
+	if s.Loads == 0 {
+		return 0
+	}
+	return s.LoadTime / time.Duration(s.Loads)
+}
+
+// Add 返回两份统计相加的结果
+func (s Stats) Add(o Stats) Stats {
+	return Stats{
+		Hits:        s.Hits + o.Hits,
+		Misses:      s.Misses + o.Misses,
+		Evictions:   s.Evictions + o.Evictions,
+		Expirations: s.Expirations + o.Expirations,
+		Loads:       s.Loads + o.Loads,
+		LoadErrors:  s.LoadErrors + o.LoadErrors,
+		LoadTime:    s.LoadTime + o.LoadTime,
+	}
+}
+
+// loadStats 并发安全的加载计数
+type loadStats struct {
+	loads, errors atomic.Uint64
+	nanos         atomic.Int64
+}
+
+// record 记录一次耗时为 d 的加载
+func (l *loadStats) record(d time.Duration, err error) {
+	l.loads.Add(1)
+	l.nanos.Add(int64(d))
+	if err != nil {
+		l.errors.Add(1)
+	}
+}
+
+// fill 把加载计数写入 st
+func (l *loadStats) fill(st Stats) Stats {
+	st.Loads = l.loads.Load()
+	st.LoadErrors = l.errors.Load()
+	st.LoadTime = time.Duration(l.nanos.Load())
+	return st
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 命中、未命中、淘汰、过期计数
+func TestCacheStats(t *testing.T) {
+	c := New[string, int](WithShards(1), WithMaxEntries(2))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Get("x")
+	c.Set("c", 3) // 淘汰 b
+	c.SetWithTTL("d", 4, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	c.Get("d")
+
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 2 {
+		t.Fatalf("hits/misses = %d/%d, want 1/2", st.Hits, st.Misses)
+	}
+	// 写入 d 时淘汰 a，读取 d 时过期
+	if st.Evictions != 2 || st.Expirations != 1 {
+		t.Fatalf("evictions/expirations = %d/%d, want 2/1", st.Evictions, st.Expirations)
+	}
+	if r := st.HitRatio(); r < 0.33 || r > 0.34 {
+		t.Fatalf("unexpected hit ratio %f", r)
+	}
+}
+
+// 回调在锁外执行，回调中访问缓存不会死锁
+func TestCacheHooks(t *testing.T) {
+	var evicted, expired []string
+	var c *Cache[string, int]
+	c = New[string, int](WithShards(1), WithMaxEntries(2),
+		WithOnEvict(func(k string, v int) {
+			evicted = append(evicted, k)
+			c.Len()
+		}),
+		WithOnExpire(func(k string, v int) {
+			expired = append(expired, k)
+			c.Len()
+		}),
+	)
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Millisecond)
+	c.Set("c", 3)
+	time.Sleep(2 * time.Millisecond)
+	c.DeleteExpired()
+
+	if strings.Join(evicted, ",") != "a" {
+		t.Fatalf("evicted = %v, want [a]", evicted)
+	}
+	if strings.Join(expired, ",") != "b" {
+		t.Fatalf("expired = %v, want [b]", expired)
+	}
+
+	// 类型不匹配时 panic
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for mismatched callback type")
+		}
+	}()
+	New[string, int](WithOnEvict(func(k int, v int) {}))
+}
+
+// 快照保存后恢复，剩余寿命保持不变，已过期条目被丢弃
+func TestCacheSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	src := New[string, int]()
+	src.Set("a", 1)
+	src.SetWithTTL("b", 2, time.Hour)
+	src.SetWithTTL("c", 3, 5*time.Millisecond)
+	if err := src.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	dst := New[string, int]()
+	n, err := dst.LoadFile(path)
+	if err != nil || n != 2 {
+		t.Fatalf("LoadFile = %d, %v, want 2, nil", n, err)
+	}
+	if v, ok := dst.Get("a"); !ok || v != 1 {
+		t.Fatalf("a = %d, %v", v, ok)
+	}
+	if _, ok := dst.Get("c"); ok {
+		t.Fatal("expired entry should not be restored")
+	}
+
+	// 文件不存在时视为空快照
+	if n, err := dst.LoadFile(filepath.Join(t.TempDir(), "missing")); n != 0 || err != nil {
+		t.Fatalf("missing file: %d, %v", n, err)
+	}
+	if _, err := dst.Load(strings.NewReader("garbage")); !errors.Is(err, ErrBadSnapshot) {
+		t.Fatalf("expected ErrBadSnapshot, got %v", err)
+	}
+}
+
+// LoadingCache 统计加载次数，快照恢复后不再调用 Loader
+func TestLoadingCacheStatsAndSnapshot(t *testing.T) {
+	var calls atomic.Int32
+	loader := func(_ context.Context, k string) (string, error) {
+		calls.Add(1)
+		if k == "missing" {
+			return "", ErrNotFound
+		}
+		return "v-" + k, nil
+	}
+	c := NewLoadingCache(loader, WithTTL(time.Hour))
+	ctx := context.Background()
+	c.Get(ctx, "a")
+	c.Get(ctx, "a")
+	c.Get(ctx, "missing")
+
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 2 || st.Loads != 2 || st.LoadErrors != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	warm := NewLoadingCache(loader, WithTTL(time.Hour))
+	if n, err := warm.Load(&buf); err != nil || n != 1 {
+		t.Fatalf("Load = %d, %v", n, err)
+	}
+	calls.Store(0)
+	if v, err := warm.Get(ctx, "a"); err != nil || v != "v-a" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if calls.Load() != 0 {
+		t.Fatal("restored entry should not be reloaded")
+	}
+}
@@ -0,0 +1,318 @@
+package cache
+
+import "container/list"
+
+// W-TinyLFU 相关参数
+const (
+	tinyLFUWindowPercent    = 1       // 窗口区占总容量的百分比
+	tinyLFUProtectedPercent = 80      // 主区中保护段占的百分比
+	tinyLFUSampleFactor     = 10      // 每写入 容量*10 次频率就整体减半（老化）
+	tinyLFUDefaultCapacity  = 1 << 12 // 只按成本限制时，用于确定 sketch 大小的估计容量
+	sketchDepth             = 4
+	sketchMaxCount          = 15 // 计数器上限，与经典实现的 4bit 计数器一致
+)
+
+// 条目所在区域
+const (
+	regionWindow = iota
+	regionProbation
+	regionProtected
+)
+
+// tinyLFUItem 记录条目所在区域及在链表中的位置
+type tinyLFUItem struct {
+	region int
+	el     *list.Element
+}
+
+// tinyLFUPolicy Window-TinyLFU：
+//   - 新条目先进入一个很小的 LRU 窗口区，吸收突发流量；
+//   - 窗口区被挤出的候选者需要和主区（SLRU）的淘汰者比较访问频率，频率更高的才能留下；
+//   - 频率由 count-min sketch 估计，doorkeeper 过滤只出现过一次的 key，并定期减半实现老化。
+//
+// 这样一次性扫描的大量冷 key 无法把热点数据挤出主区。
+type tinyLFUPolicy[K comparable] struct {
+	hash func(K) uint64
+
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	items     map[K]*tinyLFUItem
+
+	windowCap    int
+	protectedCap int
+
+	sketch *countMinSketch
+	door   *doorkeeper
+
+	additions int
+	sample    int
+}
+
+func newTinyLFU[K comparable](capacity int, hash func(K) uint64) *tinyLFUPolicy[K] {
+	if capacity <= 0 {
+		capacity = tinyLFUDefaultCapacity
+	}
+	windowCap := capacity * tinyLFUWindowPercent / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	protectedCap := (capacity - windowCap) * tinyLFUProtectedPercent / 100
+	if protectedCap < 1 {
+		protectedCap = 1
+	}
+	return &tinyLFUPolicy[K]{
+		hash:         hash,
+		window:       list.New(),
+		probation:    list.New(),
+		protected:    list.New(),
+		items:        make(map[K]*tinyLFUItem),
+		windowCap:    windowCap,
+		protectedCap: protectedCap,
+		sketch:       newCountMinSketch(capacity),
+		door:         newDoorkeeper(capacity),
+		sample:       capacity * tinyLFUSampleFactor,
+	}
+}
+
+// record 记录一次访问
+func (p *tinyLFUPolicy[K]) record(h uint64) {
+	// 第一次出现只进入 doorkeeper，不占用 sketch 计数
+	if !p.door.insert(h) {
+		p.sketch.increment(h)
+	}
+	p.additions++
+	if p.additions >= p.sample {
+		p.sketch.reset()
+		p.door.reset()
+		p.additions /= 2
+	}
+}
+
+// estimate 估计访问频率
+func (p *tinyLFUPolicy[K]) estimate(key K) int {
+	h := p.hash(key)
+	n := p.sketch.estimate(h)
+	if p.door.contains(h) {
+		n++
+	}
+	return n
+}
+
+func (p *tinyLFUPolicy[K]) add(key K) {
+	if _, ok := p.items[key]; ok {
+		p.access(key)
+		return
+	}
+	p.record(p.hash(key))
+	p.items[key] = &tinyLFUItem{region: regionWindow, el: p.window.PushFront(key)}
+
+	// 尚未满员时窗口区可能超出份额，多出来的直接转入主区试用段
+	for p.window.Len() > p.windowCap {
+		p.moveTo(p.window.Back().Value.(K), regionProbation)
+	}
+}
+
+func (p *tinyLFUPolicy[K]) access(key K) {
+	it, ok := p.items[key]
+	if !ok {
+		return
+	}
+	p.record(p.hash(key))
+
+	switch it.region {
+	case regionWindow:
+		p.window.MoveToFront(it.el)
+	case regionProbation:
+		// 试用段再次命中：晋升到保护段，保护段超额时把最旧的降级回试用段
+		p.moveTo(key, regionProtected)
+		for p.protected.Len() > p.protectedCap {
+			p.moveTo(p.protected.Back().Value.(K), regionProbation)
+		}
+	case regionProtected:
+		p.protected.MoveToFront(it.el)
+	}
+}
+
+func (p *tinyLFUPolicy[K]) remove(key K) {
+	it, ok := p.items[key]
+	if !ok {
+		return
+	}
+	p.list(it.region).Remove(it.el)
+	delete(p.items, key)
+}
+
+func (p *tinyLFUPolicy[K]) victim() (K, bool) {
+	mainVictim, hasMain := p.mainVictim()
+
+	// 窗口区满员时，窗口尾部的候选者与主区淘汰者比较频率
+	if p.window.Len() > 0 && p.window.Len() >= p.windowCap {
+		candidate := p.window.Back().Value.(K)
+		if !hasMain {
+			return candidate, true
+		}
+		if p.estimate(candidate) > p.estimate(mainVictim) {
+			// 候选者胜出：进入试用段，淘汰主区的条目
+			p.moveTo(candidate, regionProbation)
+			return mainVictim, true
+		}
+		return candidate, true
+	}
+
+	if hasMain {
+		return mainVictim, true
+	}
+	if el := p.window.Back(); el != nil {
+		return el.Value.(K), true
+	}
+	var zero K
+	return zero, false
+}
+
+// mainVictim 主区的淘汰者：优先试用段尾部，其次保护段尾部
+func (p *tinyLFUPolicy[K]) mainVictim() (K, bool) {
+	if el := p.probation.Back(); el != nil {
+		return el.Value.(K), true
+	}
+	if el := p.protected.Back(); el != nil {
+		return el.Value.(K), true
+	}
+	var zero K
+	return zero, false
+}
+
+// moveTo 把条目移动到指定区域的头部
+func (p *tinyLFUPolicy[K]) moveTo(key K, region int) {
+	it := p.items[key]
+	p.list(it.region).Remove(it.el)
+	it.region = region
+	it.el = p.list(region).PushFront(key)
+}
+
+func (p *tinyLFUPolicy[K]) list(region int) *list.List {
+	switch region {
+	case regionProbation:
+		return p.probation
+	case regionProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+// ---- count-min sketch ----
+
+// countMinSketch 频率估计：depth 行计数器，取各行最小值作为估计
+type countMinSketch struct {
+	rows [sketchDepth][]uint8
+	mask uint64
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := nextPowerOfTwo(capacity)
+	s := &countMinSketch{mask: uint64(width - 1)}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index 用双重哈希为第 i 行计算下标
+func (s *countMinSketch) index(h uint64, i int) uint64 {
+	h1, h2 := spread(h)
+	return (h1 + uint64(i)*h2) & s.mask
+}
+
+func (s *countMinSketch) increment(h uint64) {
+	for i := range s.rows {
+		idx := s.index(h, i)
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+		}
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) int {
+	least := uint8(sketchMaxCount)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < least {
+			least = v
+		}
+	}
+	return int(least)
+}
+
+// reset 所有计数器减半，让历史热点逐渐冷却
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+}
+
+// ---- doorkeeper ----
+
+// doorkeeper 一个小型布隆过滤器，只有第二次出现的 key 才会进入 sketch
+type doorkeeper struct {
+	bits []uint64
+	mask uint64
+}
+
+func newDoorkeeper(capacity int) *doorkeeper {
+	// 每个 key 约 8 bit，两个哈希函数
+	n := nextPowerOfTwo(capacity * 8)
+	if n < 64 {
+		n = 64
+	}
+	return &doorkeeper{bits: make([]uint64, n/64), mask: uint64(n - 1)}
+}
+
+// insert 写入 h，返回写入前是否已经存在（两个 bit 都已置位）
+func (d *doorkeeper) insert(h uint64) bool {
+	h1, h2 := spread(h)
+	present := true
+	for _, x := range [2]uint64{h1 & d.mask, h2 & d.mask} {
+		word, bit := x/64, uint64(1)<<(x%64)
+		if d.bits[word]&bit == 0 {
+			present = false
+			d.bits[word] |= bit
+		}
+	}
+	return present
+}
+
+func (d *doorkeeper) contains(h uint64) bool {
+	h1, h2 := spread(h)
+	for _, x := range [2]uint64{h1 & d.mask, h2 & d.mask} {
+		if d.bits[x/64]&(uint64(1)<<(x%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *doorkeeper) reset() {
+	clear(d.bits)
+}
+
+// spread 把一个哈希值拆成两个相互独立的哈希。
+// 分片下标用的是哈希低位，同一分片内低位相同，这里先混洗再取值。
+func spread(h uint64) (uint64, uint64) {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h, h>>32 | h<<32 | 1
+}
+
+// nextPowerOfTwo 不小于 n 的最小 2 的幂
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
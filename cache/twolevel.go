@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// TwoLevelOptions 控制二级缓存行为
+type TwoLevelOptions struct {
+	L1      []Option      // 本地 L1 缓存的配置
+	L1TTL   time.Duration // L1 条目的过期时间，也是丢失失效通知时脏数据的最长存活时间
+	L2TTL   time.Duration // Redis 中条目的过期时间（0 表示永不过期）
+	Prefix  string        // Redis key 前缀
+	Channel string        // 失效通知使用的 Pub/Sub 频道
+	Codec   Codec         // 值的编解码方式
+	NodeID  string        // 当前节点标识，用于忽略自己发出的通知（默认随机生成）
+}
+
+// 一些默认值
+const (
+	defaultL1TTL   = time.Minute
+	defaultL2TTL   = 10 * time.Minute
+	defaultChannel = "gopulse:cache:invalidate"
+)
+
+// DefaultTwoLevelOptions 默认配置
+func DefaultTwoLevelOptions() TwoLevelOptions {
+	return TwoLevelOptions{
+		L1TTL:   defaultL1TTL,
+		L2TTL:   defaultL2TTL,
+		Channel: defaultChannel,
+		Codec:   JSONCodec{},
+	}
+}
+
+// TwoLevelOption 函数式编程
+type TwoLevelOption func(*TwoLevelOptions)
+
+// WithL1 初始化 L1 缓存配置
+func WithL1(opts ...Option) TwoLevelOption {
+	return func(o *TwoLevelOptions) {
+		o.L1 = opts
+	}
+}
+
+// WithL1TTL 初始化 L1TTL
+func WithL1TTL(d time.Duration) TwoLevelOption {
+	return func(o *TwoLevelOptions) {
+		o.L1TTL = d
+	}
+}
+
+// WithL2TTL 初始化 L2TTL
+func WithL2TTL(d time.Duration) TwoLevelOption {
+	return func(o *TwoLevelOptions) {
+		o.L2TTL = d
+	}
+}
+
+// WithPrefix 初始化 Prefix
+func WithPrefix(prefix string) TwoLevelOption {
+	return func(o *TwoLevelOptions) {
+		o.Prefix = prefix
+	}
+}
+
+// WithChannel 初始化 Channel
+func WithChannel(channel string) TwoLevelOption {
+	return func(o *TwoLevelOptions) {
+		o.Channel = channel
+	}
+}
+
+// WithCodec 初始化 Codec
+func WithCodec(codec Codec) TwoLevelOption {
+	return func(o *TwoLevelOptions) {
+		o.Codec = codec
+	}
+}
+
+// WithNodeID 初始化 NodeID
+func WithNodeID(id string) TwoLevelOption {
+	return func(o *TwoLevelOptions) {
+		o.NodeID = id
+	}
+}
+
+// invalidation 节点之间广播的失效消息
+type invalidation struct {
+	Node string `json:"node"`
+	Key  string `json:"key"`
+}
+
+// TwoLevel 二级缓存：进程内 L1 在前，Redis L2 在后。
+// 写入和删除会通过 Redis Pub/Sub 广播给其他节点，收到通知的节点淘汰自己的 L1 副本。
+// 通知是尽力而为的（例如订阅连接断开期间的消息会丢失），L1TTL 决定了脏数据的最长存活时间。
+type TwoLevel[V any] struct {
+	rdb *redis.Client
+	l1  *Cache[string, V]
+	cfg TwoLevelOptions
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+	once   sync.Once
+
+	l2 loadStats // 读取 L2 的次数与耗时
+}
+
+// NewTwoLevel 创建二级缓存，并在返回前确认失效频道已订阅成功
+func NewTwoLevel[V any](ctx context.Context, rdb *redis.Client, opts ...TwoLevelOption) (*TwoLevel[V], error) {
+	// 默认配置
+	cfg := DefaultTwoLevelOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = JSONCodec{}
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = defaultChannel
+	}
+	if cfg.NodeID == "" {
+		cfg.NodeID = uuid.NewString()
+	}
+
+	pubsub := rdb.Subscribe(ctx, cfg.Channel)
+	// 等待订阅确认，保证返回后不会漏掉其他节点的通知
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	c := &TwoLevel[V]{
+		rdb:    rdb,
+		l1:     New[string, V](append(cfg.L1, WithTTL(cfg.L1TTL))...),
+		cfg:    cfg,
+		pubsub: pubsub,
+		done:   make(chan struct{}),
+	}
+	go c.listen()
+	return c, nil
+}
+
+// Get 依次查询 L1、L2，L2 命中时回填 L1；都未命中返回 ErrNotFound
+func (c *TwoLevel[V]) Get(ctx context.Context, key string) (V, error) {
+	var zero V
+	if v, ok := c.l1.Get(key); ok {
+		return v, nil
+	}
+
+	start := time.Now()
+	data, err := c.rdb.Get(ctx, c.cfg.Prefix+key).Bytes()
+	c.l2.record(time.Since(start), err)
+	if errors.Is(err, redis.Nil) {
+		return zero, ErrNotFound
+	}
+	if err != nil {
+		return zero, err
+	}
+
+	var v V
+	if err := c.cfg.Codec.Unmarshal(data, &v); err != nil {
+		return zero, err
+	}
+	c.l1.Set(key, v)
+	return v, nil
+}
+
+// Set 写入 L2 和本地 L1，并通知其他节点淘汰 L1 副本
+func (c *TwoLevel[V]) Set(ctx context.Context, key string, value V) error {
+	data, err := c.cfg.Codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := c.rdb.Set(ctx, c.cfg.Prefix+key, data, c.cfg.L2TTL).Err(); err != nil {
+		return err
+	}
+	c.l1.Set(key, value)
+	return c.publish(ctx, key)
+}
+
+// Delete 删除 L2 和本地 L1，并通知其他节点淘汰 L1 副本
+func (c *TwoLevel[V]) Delete(ctx context.Context, key string) error {
+	if err := c.rdb.Del(ctx, c.cfg.Prefix+key).Err(); err != nil {
+		return err
+	}
+	c.l1.Delete(key)
+	return c.publish(ctx, key)
+}
+
+// Stats 返回统计信息：命中、淘汰、过期来自 L1，Loads 为 L1 未命中后读取 L2 的次数，
+// LoadErrors 包括 L2 中不存在的情况
+func (c *TwoLevel[V]) Stats() Stats {
+	return c.l2.fill(c.l1.Stats())
+}
+
+// SaveFile 把 L1 快照保存到文件，见 Cache.SaveFile
+func (c *TwoLevel[V]) SaveFile(path string) error {
+	return c.l1.SaveFile(path)
+}
+
+// LoadFile 从文件恢复 L1，文件不存在时返回 0, nil。
+// 停机期间错过的失效通知无法补回，恢复的条目最多在 L1TTL 内可能是旧值。
+func (c *TwoLevel[V]) LoadFile(path string) (int, error) {
+	return c.l1.LoadFile(path)
+}
+
+// Close 取消订阅并停止本地缓存，可重复调用
+func (c *TwoLevel[V]) Close() error {
+	var err error
+	c.once.Do(func() {
+		err = c.pubsub.Close()
+		<-c.done
+		c.l1.Close()
+	})
+	return err
+}
+
+// publish 广播失效消息
+func (c *TwoLevel[V]) publish(ctx context.Context, key string) error {
+	msg, err := json.Marshal(invalidation{Node: c.cfg.NodeID, Key: key})
+	if err != nil {
+		return err
+	}
+	return c.rdb.Publish(ctx, c.cfg.Channel, msg).Err()
+}
+
+// listen 处理其他节点的失效通知，直到订阅关闭
+func (c *TwoLevel[V]) listen() {
+	defer close(c.done)
+
+	for msg := range c.pubsub.Channel() {
+		var inv invalidation
+		if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+			continue
+		}
+		// 自己发出的通知不用处理，本地 L1 已经是最新值
+		if inv.Node == c.cfg.NodeID {
+			continue
+		}
+		c.l1.Delete(inv.Key)
+	}
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newTwoLevelNode(t *testing.T, mr *miniredis.Miniredis, opts ...TwoLevelOption) *TwoLevel[user] {
+	t.Helper()
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	c, err := NewTwoLevel[user](context.Background(), rdb, append([]TwoLevelOption{WithPrefix("test:")}, opts...)...)
+	if err != nil {
+		t.Fatalf("new two level cache: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// 等待条件成立，用于等待异步的失效通知
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 测试 L1 未命中时从 L2 读取并回填
+func TestTwoLevelReadThrough(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTwoLevelNode(t, mr)
+	ctx := context.Background()
+
+	if _, err := a.Get(ctx, "u1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := a.Set(ctx, "u1", user{ID: 1, Name: "alice"}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if !mr.Exists("test:u1") {
+		t.Fatalf("value should be written to redis with prefix")
+	}
+
+	// 直接删除 Redis 中的值，L1 依然能命中
+	mr.Del("test:u1")
+	if u, err := a.Get(ctx, "u1"); err != nil || u.Name != "alice" {
+		t.Fatalf("l1 should serve value, got %v %v", u, err)
+	}
+}
+
+// 测试一个节点写入后，其他节点的 L1 副本被淘汰
+func TestTwoLevelCrossNodeInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTwoLevelNode(t, mr, WithCodec(GobCodec{}))
+	b := newTwoLevelNode(t, mr, WithCodec(GobCodec{}))
+	ctx := context.Background()
+
+	if err := a.Set(ctx, "u1", user{ID: 1, Name: "alice"}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if u, err := b.Get(ctx, "u1"); err != nil || u.Name != "alice" {
+		t.Fatalf("b should read from l2, got %v %v", u, err)
+	}
+
+	// a 更新后，b 的 L1 应被淘汰，再次读取拿到新值
+	if err := a.Set(ctx, "u1", user{ID: 1, Name: "bob"}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	eventually(t, func() bool {
+		u, err := b.Get(ctx, "u1")
+		return err == nil && u.Name == "bob"
+	})
+
+	// a 删除后，b 读取返回 ErrNotFound
+	if err := a.Delete(ctx, "u1"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	eventually(t, func() bool {
+		_, err := b.Get(ctx, "u1")
+		return errors.Is(err, ErrNotFound)
+	})
+}
+
+// 测试 L2 TTL
+func TestTwoLevelL2TTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTwoLevelNode(t, mr, WithL2TTL(time.Minute))
+
+	if err := a.Set(context.Background(), "u1", user{ID: 1}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if ttl := mr.TTL("test:u1"); ttl != time.Minute {
+		t.Fatalf("unexpected l2 ttl: %v", ttl)
+	}
+}
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// shouldRecomputeEarly 实现 XFetch（Optimal Probabilistic Cache Stampede Prevention）：
+//
+//	now - delta * beta * ln(rand()) >= expiry
+//
+// delta 是上一次重算的耗时。越接近过期、重算越慢，提前重算的概率越高；
+// 不同进程各自独立抽样，过期时间相同的大量 key 会被分散到过期前的不同时刻刷新。
+func shouldRecomputeEarly(now, expireAt time.Time, delta time.Duration, beta float64) bool {
+	if beta <= 0 || delta <= 0 || expireAt.IsZero() {
+		return false
+	}
+	r := rand.Float64()
+	if r == 0 {
+		// ln(0) 为负无穷，视为一定重算
+		return true
+	}
+	gap := time.Duration(float64(delta) * beta * -math.Log(r))
+	return !now.Add(gap).Before(expireAt)
+}
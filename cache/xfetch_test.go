@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 统计提前重算的概率
+func recomputeRate(remaining, delta time.Duration, beta float64) float64 {
+	now := time.Now()
+	expireAt := now.Add(remaining)
+	hits := 0
+	const n = 10000
+	for i := 0; i < n; i++ {
+		if shouldRecomputeEarly(now, expireAt, delta, beta) {
+			hits++
+		}
+	}
+	return float64(hits) / n
+}
+
+// 越接近过期、重算成本越高，提前重算的概率越大
+func TestShouldRecomputeEarly(t *testing.T) {
+	if shouldRecomputeEarly(time.Now(), time.Time{}, time.Second, 1) {
+		t.Fatalf("entries without expiry should never recompute early")
+	}
+	if shouldRecomputeEarly(time.Now(), time.Now().Add(time.Millisecond), time.Second, 0) {
+		t.Fatalf("beta 0 should disable early recompute")
+	}
+	if !shouldRecomputeEarly(time.Now(), time.Now().Add(-time.Millisecond), time.Millisecond, 1) {
+		t.Fatalf("expired entries should always recompute")
+	}
+
+	far := recomputeRate(time.Second, 10*time.Millisecond, 1)
+	near := recomputeRate(10*time.Millisecond, 10*time.Millisecond, 1)
+	if far > 0.01 || near < 0.3 || near > 0.4 {
+		// 剩余时间等于 delta 时概率为 e^-1 ≈ 0.37
+		t.Fatalf("unexpected rates: far=%.3f near=%.3f", far, near)
+	}
+
+	cheap := recomputeRate(50*time.Millisecond, time.Millisecond, 1)
+	costly := recomputeRate(50*time.Millisecond, 50*time.Millisecond, 1)
+	if cheap >= costly {
+		t.Fatalf("costly recompute should happen earlier: cheap=%.3f costly=%.3f", cheap, costly)
+	}
+}
+
+// 接近过期时在后台提前刷新，读取方始终拿到值而不会阻塞在加载上
+func TestLoadingCacheEarlyRefresh(t *testing.T) {
+	var calls int32
+	c := NewLoadingCache(func(ctx context.Context, key string) (int32, error) {
+		time.Sleep(20 * time.Millisecond)
+		return atomic.AddInt32(&calls, 1), nil
+	}, WithTTL(100*time.Millisecond), WithEarlyRefresh(5))
+
+	if v, _ := c.Get(context.Background(), "k"); v != 1 {
+		t.Fatalf("unexpected first value: %d", v)
+	}
+
+	time.Sleep(90 * time.Millisecond)
+	start := time.Now()
+	if v, _ := c.Get(context.Background(), "k"); v != 1 {
+		t.Fatalf("old value should be served while refreshing, got %d", v)
+	}
+	if time.Since(start) > 10*time.Millisecond {
+		t.Fatalf("early refresh should not block the reader")
+	}
+
+	eventually(t, func() bool {
+		v, _ := c.Get(context.Background(), "k")
+		return v == 2
+	})
+}
+
+// 测试过期后加载失败时返回旧值
+func TestLoadingCacheStaleIfError(t *testing.T) {
+	var fail atomic.Bool
+	var notFound atomic.Bool
+	boom := errors.New("boom")
+	c := NewLoadingCache(func(ctx context.Context, key string) (string, error) {
+		if notFound.Load() {
+			return "", ErrNotFound
+		}
+		if fail.Load() {
+			return "", boom
+		}
+		return "fresh", nil
+	}, WithTTL(20*time.Millisecond), WithStaleIfError(200*time.Millisecond), WithNegativeTTL(time.Minute))
+
+	if v, err := c.Get(context.Background(), "k"); err != nil || v != "fresh" {
+		t.Fatalf("unexpected first load: %v %v", v, err)
+	}
+
+	fail.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if v, err := c.Get(context.Background(), "k"); err != nil || v != "fresh" {
+			t.Fatalf("stale value should be served on error, got %v %v", v, err)
+		}
+	}
+
+	// 数据被删除时不再返回旧值
+	notFound.Store(true)
+	if _, err := c.Get(context.Background(), "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// 超出 StaleIfError 窗口后返回错误
+func TestLoadingCacheStaleWindowExceeded(t *testing.T) {
+	var fail atomic.Bool
+	boom := errors.New("boom")
+	c := NewLoadingCache(func(ctx context.Context, key string) (string, error) {
+		if fail.Load() {
+			return "", boom
+		}
+		return "fresh", nil
+	}, WithTTL(10*time.Millisecond), WithStaleIfError(20*time.Millisecond))
+
+	c.Get(context.Background(), "k")
+	fail.Store(true)
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := c.Get(context.Background(), "k"); !errors.Is(err, boom) {
+		t.Fatalf("expected loader error after stale window, got %v", err)
+	}
+}
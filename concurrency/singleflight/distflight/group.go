@@ -0,0 +1,269 @@
+// Package distflight 通过 Redis 在多个进程之间合并同一 key 的计算：整个集群中第一个调用方拿到 dlock 租约后执行 fn，
+// 把结果写入一个短期保留的 key 并通过 Pub/Sub 通知，其他节点上的调用方等待这个结果，超时后退回本地计算。
+// 适合缓存重建等需要避免大量实例同时打到下游的场景。
+package distflight
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Nuyoahch/gopulse/concurrency/singleflight"
+	dlock "github.com/Nuyoahch/gopulse/lock/distlock"
+)
+
+// Codec 结果在 Redis 中的编解码方式
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// jsonCodec 默认的 JSON 编解码
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// Options 控制分布式合并的行为
+type Options struct {
+	Prefix       string        // Redis key 前缀（默认 "singleflight:"）
+	LeaseTTL     time.Duration // 计算期间持有的租约时长，计算期间自动续约（默认 10s）
+	ResultTTL    time.Duration // 结果在 Redis 中保留多久，期间到达的调用直接复用（默认 5s）
+	WaitTimeout  time.Duration // 其他节点计算时最多等待多久，超时后本地计算（默认 10s）
+	PollInterval time.Duration // 没有收到通知时检查结果的间隔，防止通知丢失（默认 500ms）
+	Codec        Codec         // 结果的编解码（默认 JSON）
+}
+
+// 一些默认值
+const (
+	defaultPrefix       = "singleflight:"
+	defaultLeaseTTL     = 10 * time.Second
+	defaultResultTTL    = 5 * time.Second
+	defaultWaitTimeout  = 10 * time.Second
+	defaultPollInterval = 500 * time.Millisecond
+)
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		Prefix:       defaultPrefix,
+		LeaseTTL:     defaultLeaseTTL,
+		ResultTTL:    defaultResultTTL,
+		WaitTimeout:  defaultWaitTimeout,
+		PollInterval: defaultPollInterval,
+		Codec:        jsonCodec{},
+	}
+}
+
+// Option 函数式编程
+type Option func(*Options)
+
+// WithPrefix 初始化 Prefix
+func WithPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.Prefix = prefix
+	}
+}
+
+// WithLeaseTTL 初始化 LeaseTTL
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.LeaseTTL = ttl
+	}
+}
+
+// WithResultTTL 初始化 ResultTTL
+func WithResultTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.ResultTTL = ttl
+	}
+}
+
+// WithWaitTimeout 初始化 WaitTimeout
+func WithWaitTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.WaitTimeout = d
+	}
+}
+
+// WithPollInterval 初始化 PollInterval
+func WithPollInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.PollInterval = d
+	}
+}
+
+// WithCodec 初始化 Codec
+func WithCodec(c Codec) Option {
+	return func(o *Options) {
+		o.Codec = c
+	}
+}
+
+// Group 跨进程合并同一 key 的调用，并发安全。进程内同一 key 的调用先通过 singleflight 合并，
+// 只有一个调用方会访问 Redis。Redis 出错时退回本地计算，不会因为协调失败而拿不到结果
+type Group[V any] struct {
+	rdb   *redis.Client
+	locks *dlock.Client
+	cfg   Options
+	local singleflight.Group[string, result[V]]
+}
+
+// result 一次调用的结果，remote 表示来自其他节点的计算
+type result[V any] struct {
+	val    V
+	remote bool
+}
+
+// New 创建分布式 Group，使用同一个 Redis 和 Prefix 的实例之间互相合并
+func New[V any](rdb *redis.Client, opts ...Option) *Group[V] {
+	// 默认配置
+	cfg := DefaultOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	// base case
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultPrefix
+	}
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = defaultLeaseTTL
+	}
+	if cfg.ResultTTL <= 0 {
+		cfg.ResultTTL = defaultResultTTL
+	}
+	if cfg.WaitTimeout <= 0 {
+		cfg.WaitTimeout = defaultWaitTimeout
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = jsonCodec{}
+	}
+	return &Group[V]{rdb: rdb, locks: dlock.NewClient(rdb), cfg: cfg}
+}
+
+// Do 返回 key 的结果：ResultTTL 内有其他调用算出的结果时直接复用，否则在集群中只由一个调用方执行 fn，
+// 其他调用方最多等待 WaitTimeout，超时后自己执行 fn。shared 表示结果来自其他调用。
+// fn 返回错误时不会保留结果，等待者会重新竞争租约，由下一个拿到租约的调用方再次执行 fn。
+// fn 收到的 ctx 只有在本进程内所有等待者都放弃后才会取消
+func (g *Group[V]) Do(ctx context.Context, key string, fn func(ctx context.Context) (V, error)) (V, error, bool) {
+	r, err, shared := g.local.DoContextShared(ctx, key, func(ctx context.Context) (result[V], error) {
+		return g.do(ctx, key, fn)
+	})
+	return r.val, err, shared || r.remote
+}
+
+// Forget 删除 Redis 中保留的 key 的结果，之后的调用会重新执行 fn
+func (g *Group[V]) Forget(ctx context.Context, key string) error {
+	return g.rdb.Del(ctx, g.resultKey(key)).Err()
+}
+
+// do 跨进程合并一次调用
+func (g *Group[V]) do(ctx context.Context, key string, fn func(ctx context.Context) (V, error)) (result[V], error) {
+	deadline := time.Now().Add(g.cfg.WaitTimeout)
+
+	var notify <-chan *redis.Message
+	for {
+		if v, ok, err := g.result(ctx, key); err != nil {
+			return g.fallback(ctx, fn)
+		} else if ok {
+			return result[V]{val: v, remote: true}, nil
+		}
+
+		// 租约只用于互斥，不需要 fencing token：key 的数量没有上限，不能留下永久的计数器
+		l, err := g.locks.TryLockWith(ctx, g.leaseKey(key),
+			dlock.WithTTL(g.cfg.LeaseTTL), dlock.WithAutoRenew(true), dlock.WithFencing(false))
+		if err != nil {
+			return g.fallback(ctx, fn)
+		}
+		if l != nil {
+			return g.lead(ctx, key, l, fn)
+		}
+
+		if notify == nil {
+			// 确实需要等待时才订阅（每个订阅占用一个独立的连接），
+			// 订阅成功后立即重新检查结果，避免错过检查之后、订阅之前发布的通知
+			sub := g.rdb.Subscribe(ctx, g.channel(key))
+			defer sub.Close()
+			if _, err := sub.Receive(ctx); err != nil {
+				return g.fallback(ctx, fn)
+			}
+			notify = sub.Channel()
+			continue
+		}
+
+		// 其他节点正在计算：等待通知，定期检查结果，超时后本地计算
+		wait := min(g.cfg.PollInterval, time.Until(deadline))
+		if wait <= 0 {
+			return g.fallback(ctx, fn)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result[V]{}, ctx.Err()
+		case <-notify:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// lead 持有租约时执行 fn，成功时写入结果，释放租约后通知等待者
+func (g *Group[V]) lead(ctx context.Context, key string, l *dlock.Lock, fn func(ctx context.Context) (V, error)) (result[V], error) {
+	bg := context.WithoutCancel(ctx)
+
+	// 拿到租约之前上一个持有者可能刚好写入了结果
+	if v, ok, err := g.result(ctx, key); err == nil && ok {
+		l.Unlock(bg)
+		return result[V]{val: v, remote: true}, nil
+	}
+
+	v, err := func() (V, error) {
+		// fn panic 时同样释放租约，等待者在超时前可以接手
+		defer l.Unlock(bg)
+		v, err := fn(ctx)
+		if err == nil {
+			// 写入失败只影响其他节点，它们会在超时后本地计算
+			if data, merr := g.cfg.Codec.Marshal(v); merr == nil {
+				g.rdb.Set(bg, g.resultKey(key), data, g.cfg.ResultTTL)
+			}
+		}
+		return v, err
+	}()
+	// 释放租约后再通知；失败时同样通知，让等待者立即重新竞争租约
+	g.rdb.Publish(bg, g.channel(key), "1")
+	return result[V]{val: v}, err
+}
+
+// fallback Redis 不可用或等待超时时本地计算
+func (g *Group[V]) fallback(ctx context.Context, fn func(ctx context.Context) (V, error)) (result[V], error) {
+	v, err := fn(ctx)
+	return result[V]{val: v}, err
+}
+
+// result 读取 Redis 中保留的结果
+func (g *Group[V]) result(ctx context.Context, key string) (V, bool, error) {
+	var v V
+	data, err := g.rdb.Get(ctx, g.resultKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, err
+	}
+	if err := g.cfg.Codec.Unmarshal(data, &v); err != nil {
+		return v, false, err
+	}
+	return v, true, nil
+}
+
+// Redis 中使用的 key 和 channel
+func (g *Group[V]) leaseKey(key string) string  { return g.cfg.Prefix + key + ":lease" }
+func (g *Group[V]) resultKey(key string) string { return g.cfg.Prefix + key + ":result" }
+func (g *Group[V]) channel(key string) string   { return g.cfg.Prefix + key + ":done" }
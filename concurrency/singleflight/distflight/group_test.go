@@ -0,0 +1,203 @@
+package distflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newNodes 模拟 n 个进程：各自使用独立的 Redis 连接和 Group
+func newNodes(t *testing.T, s *miniredis.Miniredis, n int, opts ...Option) []*Group[string] {
+	t.Helper()
+	groups := make([]*Group[string], n)
+	for i := range groups {
+		rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+		t.Cleanup(func() { rdb.Close() })
+		groups[i] = New[string](rdb, opts...)
+	}
+	return groups
+}
+
+// 多个节点并发调用同一 key，只有一个节点执行 fn，其他节点拿到它的结果
+func TestDoAcrossNodes(t *testing.T) {
+	s := miniredis.RunT(t)
+	nodes := newNodes(t, s, 4, WithPollInterval(20*time.Millisecond))
+
+	var counter int32
+	started := make(chan struct{})
+	block := make(chan struct{})
+	fn := func(context.Context) (string, error) {
+		if atomic.AddInt32(&counter, 1) == 1 {
+			close(started)
+		}
+		<-block
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan string, len(nodes))
+	shared := make(chan bool, len(nodes))
+	call := func(g *Group[string]) {
+		defer wg.Done()
+		v, err, sh := g.Do(context.Background(), "k", fn)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		results <- v
+		shared <- sh
+	}
+	wg.Add(1)
+	go call(nodes[0])
+	<-started
+	for _, g := range nodes[1:] {
+		wg.Add(1)
+		go call(g)
+	}
+	// 让其他节点进入等待
+	time.Sleep(50 * time.Millisecond)
+	close(block)
+	wg.Wait()
+	close(results)
+	close(shared)
+
+	for v := range results {
+		if v != "value" {
+			t.Fatalf("unexpected value: %q", v)
+		}
+	}
+	n := 0
+	for sh := range shared {
+		if sh {
+			n++
+		}
+	}
+	if n != len(nodes)-1 {
+		t.Fatalf("expected %d shared results, got %d", len(nodes)-1, n)
+	}
+	if got := atomic.LoadInt32(&counter); got != 1 {
+		t.Fatalf("fn should be called once across nodes, got %d", got)
+	}
+
+	// ResultTTL 内到达的调用直接复用结果，不需要订阅（不会建立新的连接）
+	conns := s.TotalConnectionCount()
+	v, err, sh := nodes[2].Do(context.Background(), "k", func(context.Context) (string, error) {
+		t.Errorf("fn should not be called within ResultTTL")
+		return "", nil
+	})
+	if err != nil || v != "value" || !sh {
+		t.Fatalf("unexpected reused result: %q %v %v", v, err, sh)
+	}
+	if got := s.TotalConnectionCount(); got != conns {
+		t.Fatalf("fast path should not open a subscription, connections %d -> %d", conns, got)
+	}
+	// 租约不留下 fencing 计数器
+	for _, k := range s.Keys() {
+		if k != "singleflight:k:result" {
+			t.Fatalf("unexpected key left in Redis: %q", k)
+		}
+	}
+	if s.TTL("singleflight:k:result") <= 0 {
+		t.Fatalf("result key should have a TTL")
+	}
+
+	// Forget 后重新计算
+	if err := nodes[0].Forget(context.Background(), "k"); err != nil {
+		t.Fatal(err)
+	}
+	v, _, sh = nodes[1].Do(context.Background(), "k", func(context.Context) (string, error) { return "new", nil })
+	if v != "new" || sh {
+		t.Fatalf("expected a fresh call after Forget, got %q %v", v, sh)
+	}
+}
+
+// 其他节点的计算超过 WaitTimeout 时本地计算
+func TestDoWaitTimeout(t *testing.T) {
+	s := miniredis.RunT(t)
+	nodes := newNodes(t, s, 2, WithWaitTimeout(50*time.Millisecond), WithPollInterval(10*time.Millisecond))
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		nodes[0].Do(context.Background(), "k", func(context.Context) (string, error) {
+			close(started)
+			<-block
+			return "slow", nil
+		})
+	}()
+	<-started
+
+	start := time.Now()
+	v, err, sh := nodes[1].Do(context.Background(), "k", func(context.Context) (string, error) { return "local", nil })
+	if err != nil || v != "local" || sh {
+		t.Fatalf("expected local fallback, got %q %v %v", v, err, sh)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatalf("fallback should happen after WaitTimeout")
+	}
+	close(block)
+	<-done
+}
+
+// 持有租约的节点计算失败时，等待者重新竞争租约并由其中一个计算
+func TestDoLeaderError(t *testing.T) {
+	s := miniredis.RunT(t)
+	nodes := newNodes(t, s, 2, WithPollInterval(time.Second))
+
+	started := make(chan struct{})
+	block := make(chan struct{})
+	errFail := errors.New("fail")
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err, _ := nodes[0].Do(context.Background(), "k", func(context.Context) (string, error) {
+			close(started)
+			<-block
+			return "", errFail
+		})
+		leaderErr <- err
+	}()
+	<-started
+
+	res := make(chan string, 1)
+	go func() {
+		v, _, _ := nodes[1].Do(context.Background(), "k", func(context.Context) (string, error) { return "retry", nil })
+		res <- v
+	}()
+	time.Sleep(30 * time.Millisecond)
+	close(block)
+
+	if err := <-leaderErr; !errors.Is(err, errFail) {
+		t.Fatalf("expected leader error, got %v", err)
+	}
+	select {
+	case v := <-res:
+		// 失败的通知会立即唤醒等待者，不需要等到 PollInterval
+		if v != "retry" {
+			t.Fatalf("unexpected value: %q", v)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("follower should take over after leader failure")
+	}
+	if s.Exists("singleflight:k:lease") {
+		t.Fatalf("lease should be released")
+	}
+}
+
+// Redis 不可用时退回本地计算
+func TestDoRedisDown(t *testing.T) {
+	s := miniredis.RunT(t)
+	nodes := newNodes(t, s, 1)
+	s.Close()
+
+	v, err, _ := nodes[0].Do(context.Background(), "k", func(context.Context) (string, error) { return "local", nil })
+	if err != nil || v != "local" {
+		t.Fatalf("expected local fallback, got %q %v", v, err)
+	}
+}
@@ -1,140 +1,394 @@
 package singleflight
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
 	"sync"
+	"time"
 )
 
+// ErrGoexit fn 调用了 runtime.Goexit，通过 DoChan、DoContext 等待的调用方会收到它
+var ErrGoexit = errors.New("singleflight: runtime.Goexit was called")
+
+// PanicError fn panic 时通过 DoChan、DoContext 等待的调用方收到的错误，
+// 通过 Do 调用的 leader 和其他调用方会以它重新 panic
+type PanicError struct {
+	Value any    // recover 得到的值
+	Stack []byte // panic 时 fn 所在 goroutine 的调用栈
+}
+
+// Error 实现 error
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap panic 的值是 error 时返回它
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// newPanicError 记录 panic 的值和调用栈
+func newPanicError(v any) error {
+	stack := debug.Stack()
+	// 第一行是 "goroutine N [status]:"，对应的 goroutine 在重新 panic 时可能已经退出，去掉以免误导
+	if line := bytes.IndexByte(stack, '\n'); line >= 0 {
+		stack = stack[line+1:]
+	}
+	return &PanicError{Value: v, Stack: stack}
+}
+
 // Result 是一次调用返回的结果结构体。
-type Result struct {
-	Val    any   // 业务返回值
+type Result[V any] struct {
+	Val    V     // 业务返回值
 	Err    error // 业务错误
-	Shared bool  // 是否为“复用”的结果
+	Shared bool  // 结果是否被多个调用方共享，leader 的结果被其他调用方复用时同样为 true
+}
+
+// Options Group 的配置
+type Options struct {
+	ResultTTL time.Duration // 调用成功后结果保留多久，期间同一 key 的调用直接复用，不再执行 fn（默认 0，不保留）
+	ErrorTTL  time.Duration // 调用返回错误时结果保留多久，一般比 ResultTTL 短（默认 0，不保留）
+}
+
+// DefaultOptions 默认配置：只合并进行中的调用
+func DefaultOptions() Options {
+	return Options{}
+}
+
+// Option 函数式编程
+type Option func(*Options)
+
+// WithResultTTL 初始化 ResultTTL
+func WithResultTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.ResultTTL = ttl
+	}
+}
+
+// WithErrorTTL 初始化 ErrorTTL
+func WithErrorTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.ErrorTTL = ttl
+	}
 }
 
 // Group 用于管理同一 key 的并发调用，使其在同一时间只会执行一次。
-type Group struct {
-	mu sync.Mutex
-	m  map[string]*call
+// 零值可以直接使用；需要在调用结束后短暂保留结果时通过 NewGroup 创建
+type Group[K comparable, V any] struct {
+	cfg Options
+
+	mu   sync.Mutex
+	m    map[K]*call[V]
+	memo map[K]*memo[V] // 刚结束的调用的结果
+}
+
+// NewGroup 创建 Group
+func NewGroup[K comparable, V any](opts ...Option) *Group[K, V] {
+	// 默认配置
+	cfg := DefaultOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	return &Group[K, V]{cfg: cfg}
+}
+
+// memo 一个保留的结果
+type memo[V any] struct {
+	val      V
+	err      error
+	expireAt time.Time
+	timer    *time.Timer // 到期后从 Group 中删除
 }
 
 // call 代表一次正在进行中的调用。
-type call struct {
-	wg        sync.WaitGroup  // 等待调用结束
-	val       any             // 业务返回值
-	err       error           // 业务错误
-	dups      int             // 复用次数
-	chans     []chan<- Result // DoChan/DoContext 这种通过 channel 等待结果的调用者列表
-	forgotten bool            // 是否被 Forget 标记（避免结束时重复 delete）
+type call[V any] struct {
+	wg        sync.WaitGroup     // 等待调用结束
+	val       V                  // 业务返回值
+	err       error              // 业务错误，fn panic 或 Goexit 时为 *PanicError 或 ErrGoexit
+	dups      int                // 复用次数
+	chans     []chan<- Result[V] // DoChan/DoContext 这种通过 channel 等待结果的调用者列表
+	forgotten bool               // 是否被 Forget 标记（避免结束时重复 delete）
+
+	waiters int                // 仍在等待结果的调用方个数，只有 DoContextShared、DoContext 的调用方会中途放弃
+	cancel  context.CancelFunc // DoContextShared 发起的调用传给 fn 的 ctx 的取消函数，其他调用为 nil
 }
 
-// Do 确保同一个 key 的 fn 在同一时间只会被执行一次。
-func (g *Group) Do(key string, fn func() (any, error)) (any, error, bool) {
+// Do 确保同一个 key 的 fn 在同一时间只会被执行一次，返回的 shared 含义与 Result.Shared 相同。
+// fn panic 时所有通过 Do 等待的调用方（包括 leader）都会以 *PanicError 重新 panic，
+// fn 调用 runtime.Goexit 时它们同样调用 runtime.Goexit
+func (g *Group[K, V]) Do(key K, fn func() (V, error)) (V, error, bool) {
 	g.mu.Lock()
+	if m, ok := g.recent(key); ok {
+		g.mu.Unlock()
+		return m.val, m.err, true
+	}
 	if g.m == nil {
-		g.m = make(map[string]*call)
+		g.m = make(map[K]*call[V])
 	}
 	if c, ok := g.m[key]; ok {
 		// 已有同在 key 调用在执行，复用
 		c.dups++
+		c.waiters++
 		g.mu.Unlock()
 		c.wg.Wait()
+
+		if e, ok := c.err.(*PanicError); ok {
+			panic(e)
+		} else if c.err == ErrGoexit {
+			runtime.Goexit()
+		}
 		return c.val, c.err, true
 	}
 
 	// 当前 goroutine 成为“leader”
-	c := new(call)
+	c := &call[V]{waiters: 1}
 	c.wg.Add(1)
 	g.m[key] = c
 	g.mu.Unlock()
 
 	// 同步执行用户逻辑
-	c.run(g, key, fn)
+	g.doCall(c, key, fn, true)
 
 	return c.val, c.err, c.dups > 0
 }
 
 // DoChan 和 Do 类似，但返回一个 channel，调用方可以 select 等待结果。
-func (g *Group) DoChan(key string, fn func() (any, error)) <-chan Result {
-	ch := make(chan Result, 1)
+// fn panic 或 Goexit 时 channel 收到 Err 为 *PanicError 或 ErrGoexit 的结果，不会 panic
+func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
+	ch, _ := g.doChan(key, fn)
+	return ch
+}
+
+// doChan DoChan 的实现，同时返回调用方挂上的 call，命中保留的结果时 call 为 nil
+func (g *Group[K, V]) doChan(key K, fn func() (V, error)) (<-chan Result[V], *call[V]) {
+	ch := make(chan Result[V], 1)
 
 	g.mu.Lock()
+	if m, ok := g.recent(key); ok {
+		g.mu.Unlock()
+		ch <- Result[V]{Val: m.val, Err: m.err, Shared: true}
+		close(ch)
+		return ch, nil
+	}
 	if g.m == nil {
-		g.m = make(map[string]*call)
+		g.m = make(map[K]*call[V])
 	}
 
 	if c, ok := g.m[key]; ok {
 		// 已有同 key 调用在执行，复用，只需把自己的 channel 加进去
 		c.dups++
+		c.waiters++
 		c.chans = append(c.chans, ch)
 		g.mu.Unlock()
-		return ch
+		return ch, c
 	}
 
 	// 当前 goroutine 成为“leader”，但这里我们用 goroutine 异步执行 fn
-	c := new(call)
+	c := &call[V]{waiters: 1}
 	c.wg.Add(1)
 	c.chans = append(c.chans, ch)
 	g.m[key] = c
 	g.mu.Unlock()
 
-	go c.run(g, key, fn)
-	return ch
+	go g.doCall(c, key, fn, false)
+	return ch, c
 }
 
 // DoContext 在 DoChan 的基础上加了 context 支持，
-func (g *Group) DoContext(ctx context.Context, key string, fn func() (any, error)) (any, error, bool) {
+func (g *Group[K, V]) DoContext(ctx context.Context, key K, fn func() (V, error)) (V, error, bool) {
 	// 当 ctx 先结束时，会返回 ctx.Err()，但内部 fn 依然会继续执行并可被其他调用复用。
-	ch := g.DoChan(key, fn)
+	ch, c := g.doChan(key, fn)
 
 	select {
 	case <-ctx.Done():
-		// 自己这次调用不再关心结果，返回 ctx.Err
-		return nil, ctx.Err(), false
+		// 自己这次调用不再关心结果，返回 ctx.Err；挂在 DoContextShared 发起的调用上时不再算作等待者
+		if c != nil {
+			g.leave(c, key)
+		}
+		var zero V
+		return zero, ctx.Err(), false
 	case res := <-ch:
 		return res.Val, res.Err, res.Shared
 	}
 }
 
-// Forget 让 group 忘记某个 key，
-func (g *Group) Forget(key string) {
-	// 这样即便该 key 对应的调用还在执行，之后对同一 key 的 Do/DoChan 将会触发新的调用。
+// DoContextShared 和 DoContext 类似，但 fn 会收到一个共享的 ctx：它继承发起调用的 ctx 中的值，
+// 但不受其取消和超时影响，只有当所有通过 DoContextShared、DoContext 等待的调用方的 ctx 都结束后才会被取消，
+// 此时 key 同时被 Forget，之后的调用会重新执行 fn。有 Do、DoChan 的调用方在等待时 ctx 不会被取消。
+// fn 总是在新的 goroutine 中执行，panic 或 Goexit 时返回 *PanicError 或 ErrGoexit
+func (g *Group[K, V]) DoContextShared(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error, bool) {
+	ch := make(chan Result[V], 1)
+
 	g.mu.Lock()
+	if m, ok := g.recent(key); ok {
+		g.mu.Unlock()
+		return m.val, m.err, true
+	}
 	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	c, ok := g.m[key]
+	if ok {
+		// 已有同 key 调用在执行，复用
+		c.dups++
+		c.waiters++
+		c.chans = append(c.chans, ch)
+		g.mu.Unlock()
+	} else {
+		// 当前调用方发起调用，fn 的 ctx 与它的取消解绑，由所有等待者共同决定
+		shared, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call[V]{waiters: 1, cancel: cancel}
+		c.wg.Add(1)
+		c.chans = append(c.chans, ch)
+		g.m[key] = c
 		g.mu.Unlock()
+
+		go g.doCall(c, key, func() (V, error) { return fn(shared) }, false)
+	}
+
+	select {
+	case res := <-ch:
+		return res.Val, res.Err, res.Shared
+	case <-ctx.Done():
+		g.leave(c, key)
+		var zero V
+		return zero, ctx.Err(), false
+	}
+}
+
+// leave 一个 DoContextShared 或 DoContext 的调用方放弃等待，最后一个等待者离开时取消 fn 的 ctx
+func (g *Group[K, V]) leave(c *call[V], key K) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c.waiters--
+	if c.waiters > 0 || c.cancel == nil {
 		return
 	}
-	if c, ok := g.m[key]; ok {
+	c.cancel()
+	// 被取消的调用不能再被复用，之后的调用重新执行 fn
+	if g.m[key] == c {
 		c.forgotten = true
 		delete(g.m, key)
 	}
-	g.mu.Unlock()
 }
 
-// run 真正执行 fn 的逻辑，统一处理结束后的收尾工作：
-func (c *call) run(g *Group, key string, fn func() (any, error)) {
-	// 执行用户逻辑（同步或异步都要保证 runs before cleanup）
-	c.val, c.err = fn()
-
-	// cleanup，需要在 fn 完全结束后进行
+// Forget 让 group 忘记某个 key，
+func (g *Group[K, V]) Forget(key K) {
+	// 这样即便该 key 对应的调用还在执行，之后对同一 key 的 Do/DoChan 将会触发新的调用。
+	// 保留的结果同样丢弃
 	g.mu.Lock()
-	if !c.forgotten {
+	if c, ok := g.m[key]; ok {
+		c.forgotten = true
 		delete(g.m, key)
 	}
-	chans := c.chans
-	res := Result{
-		Val:    c.val,
-		Err:    c.err,
-		Shared: c.dups > 0,
+	if m, ok := g.memo[key]; ok {
+		m.timer.Stop()
+		delete(g.memo, key)
 	}
 	g.mu.Unlock()
+}
 
-	// 唤醒等待者
-	for _, ch := range chans {
-		ch <- res
-		close(ch)
+// recent 返回 key 保留且没有过期的结果，调用方需持有 mu
+func (g *Group[K, V]) recent(key K) (*memo[V], bool) {
+	m, ok := g.memo[key]
+	if !ok || !time.Now().Before(m.expireAt) {
+		return nil, false
+	}
+	return m, true
+}
+
+// remember 按 ResultTTL、ErrorTTL 保留调用结果，panic 和 Goexit 不保留，调用方需持有 mu
+func (g *Group[K, V]) remember(key K, c *call[V]) {
+	ttl := g.cfg.ResultTTL
+	if c.err != nil {
+		ttl = g.cfg.ErrorTTL
+	}
+	// base case
+	if ttl <= 0 || c.err == ErrGoexit {
+		return
+	}
+	if _, ok := c.err.(*PanicError); ok {
+		return
+	}
+
+	if g.memo == nil {
+		g.memo = make(map[K]*memo[V])
+	}
+	if old, ok := g.memo[key]; ok {
+		old.timer.Stop()
 	}
+	m := &memo[V]{val: c.val, err: c.err, expireAt: time.Now().Add(ttl)}
+	m.timer = time.AfterFunc(ttl, func() {
+		g.mu.Lock()
+		if g.memo[key] == m {
+			delete(g.memo, key)
+		}
+		g.mu.Unlock()
+	})
+	g.memo[key] = m
+}
+
+// doCall 真正执行 fn 的逻辑，统一处理结束后的收尾工作。
+// 无论 fn 正常返回、panic 还是 Goexit，等待者都会被唤醒；repanic 为 true（Do 的 leader）时 panic 会继续向上传播
+func (g *Group[K, V]) doCall(c *call[V], key K, fn func() (V, error), repanic bool) {
+	normalReturn := false
+	recovered := false
+
+	// 双层 defer 用于区分 panic 和 Goexit：Goexit 不能被 recover，外层 defer 执行时两个标记都是 false
+	defer func() {
+		if !normalReturn && !recovered {
+			c.err = ErrGoexit
+		}
+
+		// cleanup，需要在 fn 完全结束后进行；从 map 中删除后不会再有新的等待者加入
+		g.mu.Lock()
+		if !c.forgotten {
+			delete(g.m, key)
+			g.remember(key, c)
+		}
+		if c.cancel != nil {
+			c.cancel()
+		}
+		chans := c.chans
+		res := Result[V]{
+			Val:    c.val,
+			Err:    c.err,
+			Shared: c.dups > 0,
+		}
+		g.mu.Unlock()
 
-	// 标记 fn 已完成
-	c.wg.Done()
+		// 唤醒等待者
+		c.wg.Done()
+		for _, ch := range chans {
+			ch <- res
+			close(ch)
+		}
+
+		if e, ok := c.err.(*PanicError); ok && repanic {
+			panic(e)
+		}
+	}()
+
+	func() {
+		defer func() {
+			if !normalReturn {
+				// Goexit 时 recover 返回 nil；Go 1.21 起 panic(nil) 也能 recover 到 *runtime.PanicNilError
+				if r := recover(); r != nil {
+					c.err = newPanicError(r)
+				}
+			}
+		}()
+
+		c.val, c.err = fn()
+		normalReturn = true
+	}()
+
+	if !normalReturn {
+		recovered = true
+	}
 }
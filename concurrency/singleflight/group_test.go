@@ -2,6 +2,9 @@ package singleflight
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -10,7 +13,7 @@ import (
 
 // 基础测试：单次调用
 func TestDoSingle(t *testing.T) {
-	var g Group
+	var g Group[string, any]
 
 	v, err, shared := g.Do("k", func() (any, error) {
 		return "hello", nil
@@ -28,9 +31,8 @@ func TestDoSingle(t *testing.T) {
 }
 
 // 并发测试：多个 goroutine 对同一 key 并发调用，只应执行一次 fn
-// TODO 并发测试还是存在问题，待实现
 func TestDoDuplicateSuppression(t *testing.T) {
-	var g Group
+	var g Group[string, any]
 	var counter int32
 
 	started := make(chan struct{})
@@ -49,8 +51,10 @@ func TestDoDuplicateSuppression(t *testing.T) {
 			<-block
 			return "ok", nil
 		})
-		if shared {
-			t.Errorf("first caller should not be shared")
+		// 结果被其他调用方复用过，leader 同样看到 shared=true（与 Result.Shared 的定义一致，
+		// 也与官方 x/sync/singleflight 相同；旧的断言期望 false，只是因为调用方来不及挂上而偶尔通过）
+		if !shared {
+			t.Errorf("first caller should see shared=true")
 		}
 	}()
 
@@ -63,7 +67,7 @@ func TestDoDuplicateSuppression(t *testing.T) {
 
 	// 其它 goroutine 并发调用
 	for i := 0; i < N; i++ {
-		go func(i int) {
+		go func() {
 			defer wg2.Done()
 			v, err, shared := g.Do("k", func() (any, error) {
 				t.Errorf("duplicate fn should not be called")
@@ -75,13 +79,14 @@ func TestDoDuplicateSuppression(t *testing.T) {
 			if v.(string) != "ok" {
 				t.Errorf("unexpected value: %v", v)
 			}
-			if i > 0 && !shared {
+			if !shared {
 				t.Errorf("duplicate caller should see shared=true")
 			}
-		}(i)
+		}()
 	}
 
-	// 释放阻塞，让真正的 fn 结束
+	// 等所有调用方都挂到同一个 call 上之后再释放阻塞，否则晚到的调用会发起新的 fn
+	waitDups(t, &g, "k", N)
 	close(block)
 
 	wg2.Wait()
@@ -94,7 +99,7 @@ func TestDoDuplicateSuppression(t *testing.T) {
 
 // 测试不同 key 互不影响
 func TestDoDifferentKeys(t *testing.T) {
-	var g Group
+	var g Group[string, any]
 	var c1, c2 int32
 
 	var wg sync.WaitGroup
@@ -134,7 +139,7 @@ func TestDoDifferentKeys(t *testing.T) {
 
 // 测试 DoChan 的基本行为
 func TestDoChan(t *testing.T) {
-	var g Group
+	var g Group[string, any]
 	var counter int32
 
 	resCh := g.DoChan("k", func() (any, error) {
@@ -160,7 +165,7 @@ func TestDoChan(t *testing.T) {
 
 // 测试 DoContext：ctx 先取消
 func TestDoContextCanceled(t *testing.T) {
-	var g Group
+	var g Group[string, any]
 	var counter int32
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -216,7 +221,7 @@ func TestDoContextCanceled(t *testing.T) {
 
 // 测试 Forget：在调用进行中 Forget，然后再次调用同 key，会触发第二次执行
 func TestForget(t *testing.T) {
-	var g Group
+	var g Group[string, any]
 	var counter int32
 
 	var wg sync.WaitGroup
@@ -267,3 +272,418 @@ func TestForget(t *testing.T) {
 		t.Fatalf("fn should be called twice due to Forget, got %d", got)
 	}
 }
+
+// waitDups 等待 key 上正在执行的调用有 n 个复用者
+func waitDups(t *testing.T, g *Group[string, any], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		g.mu.Lock()
+		c, ok := g.m[key]
+		done := ok && c.dups >= n
+		g.mu.Unlock()
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d duplicate callers", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 测试 Do：fn panic 时 leader 和其他调用方都以 *PanicError 重新 panic，并且不会卡住
+func TestDoPanic(t *testing.T) {
+	var g Group[string, any]
+	block := make(chan struct{})
+
+	do := func() (r any) {
+		defer func() { r = recover() }()
+		g.Do("k", func() (any, error) {
+			<-block
+			panic("boom")
+		})
+		return nil
+	}
+
+	const N = 5
+	results := make(chan any, N+1)
+	go func() { results <- do() }()
+	// 确保第一个调用成为 leader
+	waitCall(t, &g, "k")
+	for i := 0; i < N; i++ {
+		go func() { results <- do() }()
+	}
+	waitDups(t, &g, "k", N)
+	close(block)
+
+	for i := 0; i < N+1; i++ {
+		select {
+		case r := <-results:
+			e, ok := r.(*PanicError)
+			if !ok {
+				t.Fatalf("expected *PanicError, got %T %v", r, r)
+			}
+			if e.Value != "boom" {
+				t.Fatalf("unexpected panic value: %v", e.Value)
+			}
+			if len(e.Stack) == 0 {
+				t.Fatalf("expected stack trace")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("callers deadlocked after panic")
+		}
+	}
+
+	// panic 之后 key 已经清理，可以再次调用
+	v, err, _ := g.Do("k", func() (any, error) { return "ok", nil })
+	if err != nil || v != "ok" {
+		t.Fatalf("unexpected result after panic: %v %v", v, err)
+	}
+}
+
+// 测试 DoChan：fn panic 时等待者收到 *PanicError，不会 panic
+func TestDoChanPanic(t *testing.T) {
+	var g Group[string, any]
+	errBoom := errors.New("boom")
+
+	block := make(chan struct{})
+	ch1 := g.DoChan("k", func() (any, error) {
+		<-block
+		panic(errBoom)
+	})
+	ch2 := g.DoChan("k", func() (any, error) {
+		t.Errorf("duplicate fn should not be called")
+		return nil, nil
+	})
+	close(block)
+
+	for _, ch := range []<-chan Result[any]{ch1, ch2} {
+		select {
+		case res := <-ch:
+			var e *PanicError
+			if !errors.As(res.Err, &e) {
+				t.Fatalf("expected *PanicError, got %v", res.Err)
+			}
+			if !errors.Is(res.Err, errBoom) {
+				t.Fatalf("PanicError should unwrap to the panic value")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("DoChan waiter deadlocked after panic")
+		}
+	}
+}
+
+// 测试 fn 调用 runtime.Goexit：DoChan 等待者收到 ErrGoexit，Do 的复用者同样 Goexit
+func TestGoexit(t *testing.T) {
+	var g Group[string, any]
+	block := make(chan struct{})
+
+	ch := g.DoChan("k", func() (any, error) {
+		<-block
+		runtime.Goexit()
+		return nil, nil
+	})
+
+	exited := make(chan bool, 1)
+	go func() {
+		normal := false
+		defer func() { exited <- !normal }()
+		g.Do("k", func() (any, error) {
+			t.Errorf("duplicate fn should not be called")
+			return nil, nil
+		})
+		normal = true
+	}()
+	waitDups(t, &g, "k", 1)
+	close(block)
+
+	select {
+	case res := <-ch:
+		if res.Err != ErrGoexit {
+			t.Fatalf("expected ErrGoexit, got %v", res.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("DoChan waiter deadlocked after Goexit")
+	}
+	if !<-exited {
+		t.Fatalf("Do caller should Goexit too")
+	}
+}
+
+// 测试泛型的 key 与 value 类型
+func TestGeneric(t *testing.T) {
+	var g Group[int, []byte]
+	v, err, _ := g.Do(1, func() ([]byte, error) { return []byte("one"), nil })
+	if err != nil || string(v) != "one" {
+		t.Fatalf("unexpected result: %q %v", v, err)
+	}
+	res := <-g.DoChan(2, func() ([]byte, error) { return nil, errors.New("fail") })
+	if res.Err == nil || res.Val != nil {
+		t.Fatalf("unexpected result: %v", res)
+	}
+}
+
+// waitCall 等待 key 上有正在执行的调用
+func waitCall(t *testing.T, g *Group[string, any], key string) {
+	waitDups(t, g, key, 0)
+}
+
+// 测试 DoContextShared：发起者的 ctx 取消不影响 fn，其他等待者仍能拿到结果
+func TestDoContextSharedDetached(t *testing.T) {
+	var g Group[string, any]
+	type ctxKey struct{}
+
+	ctx1, cancel1 := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "v"))
+	block := make(chan struct{})
+	fnErr := make(chan error, 1)
+	done1 := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContextShared(ctx1, "k", func(ctx context.Context) (any, error) {
+			if ctx.Value(ctxKey{}) != "v" {
+				t.Errorf("shared ctx should keep the values of the caller")
+			}
+			<-block
+			fnErr <- ctx.Err()
+			return "ok", nil
+		})
+		done1 <- err
+	}()
+	waitCall(t, &g, "k")
+
+	done2 := make(chan any, 1)
+	go func() {
+		v, _, _ := g.DoContextShared(context.Background(), "k", func(context.Context) (any, error) {
+			t.Errorf("duplicate fn should not be called")
+			return nil, nil
+		})
+		done2 <- v
+	}()
+	waitDups(t, &g, "k", 1)
+
+	// 发起者放弃等待
+	cancel1()
+	if err := <-done1; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	close(block)
+	if err := <-fnErr; err != nil {
+		t.Fatalf("shared ctx should not be canceled while others wait, got %v", err)
+	}
+	if v := <-done2; v != "ok" {
+		t.Fatalf("unexpected value: %v", v)
+	}
+}
+
+// 测试 DoContextShared：所有等待者都放弃后 fn 的 ctx 被取消，之后的调用重新执行 fn
+func TestDoContextSharedAllGiveUp(t *testing.T) {
+	var g Group[string, any]
+	var counter int32
+
+	fnErr := make(chan error, 1)
+	fn := func(ctx context.Context) (any, error) {
+		if atomic.AddInt32(&counter, 1) == 1 {
+			<-ctx.Done()
+			fnErr <- ctx.Err()
+			return nil, ctx.Err()
+		}
+		return "ok", nil
+	}
+
+	const N = 3
+	var wg sync.WaitGroup
+	cancels := make([]context.CancelFunc, N)
+	for i := 0; i < N; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels[i] = cancel
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err, _ := g.DoContextShared(ctx, "k", fn); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+		}()
+		waitDups(t, &g, "k", i)
+	}
+
+	for i := 0; i < N-1; i++ {
+		cancels[i]()
+	}
+	select {
+	case <-fnErr:
+		t.Fatalf("fn canceled while a caller is still waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancels[N-1]()
+	wg.Wait()
+
+	select {
+	case err := <-fnErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected ctx error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("fn ctx should be canceled after every caller gave up")
+	}
+
+	v, err, _ := g.DoContextShared(context.Background(), "k", fn)
+	if err != nil || v != "ok" {
+		t.Fatalf("expected a fresh call, got %v %v", v, err)
+	}
+}
+
+// 测试 DoContextShared：有 Do 的调用方在等待时 fn 的 ctx 不会被取消
+func TestDoContextSharedPinnedByDo(t *testing.T) {
+	var g Group[string, any]
+	ctx, cancel := context.WithCancel(context.Background())
+
+	block := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContextShared(ctx, "k", func(ctx context.Context) (any, error) {
+			<-block
+			return "ok", ctx.Err()
+		})
+		done <- err
+	}()
+	waitCall(t, &g, "k")
+
+	res := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do("k", func() (any, error) { return nil, nil })
+		res <- err
+	}()
+	waitDups(t, &g, "k", 1)
+
+	cancel()
+	<-done
+	close(block)
+	if err := <-res; err != nil {
+		t.Fatalf("fn ctx should stay alive for Do callers, got %v", err)
+	}
+}
+
+// 测试 ResultTTL：窗口内的调用复用刚结束的结果，过期后重新执行 fn
+func TestResultTTL(t *testing.T) {
+	g := NewGroup[string, int](WithResultTTL(50 * time.Millisecond))
+	var counter int32
+	fn := func() (int, error) {
+		return int(atomic.AddInt32(&counter, 1)), nil
+	}
+
+	if v, _, shared := g.Do("k", fn); v != 1 || shared {
+		t.Fatalf("unexpected first call: %v %v", v, shared)
+	}
+	if v, _, shared := g.Do("k", fn); v != 1 || !shared {
+		t.Fatalf("call within window should reuse the result, got %v %v", v, shared)
+	}
+	if res := <-g.DoChan("k", fn); res.Val != 1 || !res.Shared {
+		t.Fatalf("DoChan within window should reuse the result, got %v", res)
+	}
+	if v, _, _ := g.DoContextShared(context.Background(), "k", func(context.Context) (int, error) { return fn() }); v != 1 {
+		t.Fatalf("DoContextShared within window should reuse the result, got %v", v)
+	}
+	// 其他 key 不受影响
+	if v, _, _ := g.Do("other", fn); v != 2 {
+		t.Fatalf("unexpected value for other key: %v", v)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if v, _, _ := g.Do("k", fn); v != 3 {
+		t.Fatalf("expired result should trigger a new call, got %v", v)
+	}
+	g.mu.Lock()
+	n := len(g.memo)
+	g.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("expired results should be removed, got %d", n)
+	}
+}
+
+// 测试 ErrorTTL：错误使用单独的窗口，默认不保留
+func TestErrorTTL(t *testing.T) {
+	var counter int32
+	fn := func() (int, error) {
+		return 0, fmt.Errorf("fail %d", atomic.AddInt32(&counter, 1))
+	}
+
+	g := NewGroup[string, int](WithResultTTL(time.Minute))
+	g.Do("k", fn)
+	if _, err, _ := g.Do("k", fn); err.Error() != "fail 2" {
+		t.Fatalf("errors should not be kept without ErrorTTL, got %v", err)
+	}
+
+	g = NewGroup[string, int](WithResultTTL(time.Minute), WithErrorTTL(30*time.Millisecond))
+	g.Do("k", fn)
+	if _, err, _ := g.Do("k", fn); err.Error() != "fail 3" {
+		t.Fatalf("error within window should be reused, got %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, err, _ := g.Do("k", fn); err.Error() != "fail 4" {
+		t.Fatalf("expired error should trigger a new call, got %v", err)
+	}
+}
+
+// 测试 Forget 丢弃保留的结果，panic 的结果不保留
+func TestResultTTLForgetAndPanic(t *testing.T) {
+	g := NewGroup[string, int](WithResultTTL(time.Minute), WithErrorTTL(time.Minute))
+	var counter int32
+	fn := func() (int, error) {
+		return int(atomic.AddInt32(&counter, 1)), nil
+	}
+
+	g.Do("k", fn)
+	g.Forget("k")
+	if v, _, _ := g.Do("k", fn); v != 2 {
+		t.Fatalf("Forget should drop the kept result, got %v", v)
+	}
+
+	res := <-g.DoChan("p", func() (int, error) { panic("boom") })
+	if _, ok := res.Err.(*PanicError); !ok {
+		t.Fatalf("expected *PanicError, got %v", res.Err)
+	}
+	if v, err, _ := g.Do("p", fn); err != nil || v != 3 {
+		t.Fatalf("panic should not be kept, got %v %v", v, err)
+	}
+}
+
+// 测试 DoContext 的调用方放弃后不再挡住共享 ctx 的取消
+func TestDoContextSharedWithDoContext(t *testing.T) {
+	var g Group[string, any]
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+
+	fnErr := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.DoContextShared(ctx1, "k", func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+			fnErr <- ctx.Err()
+			return nil, ctx.Err()
+		})
+	}()
+	waitCall(t, &g, "k")
+
+	joined := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContext(ctx2, "k", func() (any, error) { return nil, nil })
+		joined <- err
+	}()
+	waitDups(t, &g, "k", 1)
+
+	cancel2()
+	if err := <-joined; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	cancel1()
+	<-done
+	select {
+	case err := <-fnErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("unexpected ctx error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("abandoned DoContext caller should not keep the shared ctx alive")
+	}
+}
@@ -0,0 +1,157 @@
+package id
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidID 字符串或字节无法解析为 ID
+var ErrInvalidID = errors.New("id: invalid id")
+
+// crockford Crockford base32 字母表：去掉了容易混淆的 I、L、O、U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// base62 字母表按 ASCII 升序排列，定长编码后字符串顺序与数值顺序一致
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// 128 位数据编码后的长度
+const (
+	base32Len = 26 // ceil(128 / 5)
+	base62Len = 22 // ceil(128 / log2(62))
+)
+
+// 解码表，-1 表示非法字符
+var (
+	crockfordDec [256]int8
+	base62Dec    [256]int8
+)
+
+func init() {
+	for i := range crockfordDec {
+		crockfordDec[i] = -1
+		base62Dec[i] = -1
+	}
+	for i := 0; i < len(crockford); i++ {
+		c := crockford[i]
+		crockfordDec[c] = int8(i)
+		if c >= 'A' && c <= 'Z' {
+			crockfordDec[c+'a'-'A'] = int8(i)
+		}
+	}
+	// Crockford 规范：I、L 当作 1，O 当作 0，不区分大小写
+	for _, c := range "IiLl" {
+		crockfordDec[c] = 1
+	}
+	for _, c := range "Oo" {
+		crockfordDec[c] = 0
+	}
+	for i := 0; i < len(base62); i++ {
+		base62Dec[base62[i]] = int8(i)
+	}
+}
+
+// encodeBase32 把 128 位数据编码为 26 个 Crockford base32 字符。
+// 最高的 3 位单独占第一个字符，因此第一个字符不会超过 '7'，字符串顺序与数值顺序一致。
+func encodeBase32(b [16]byte) string {
+	var out [base32Len]byte
+	// 从最低位开始，每次取 5 位
+	var acc uint16
+	bits := 0
+	j := base32Len - 1
+	for i := 15; i >= 0; i-- {
+		acc |= uint16(b[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[j] = crockford[acc&0x1F]
+			j--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	out[0] = crockford[acc]
+	return string(out[:])
+}
+
+// decodeBase32 解析 encodeBase32 的结果
+func decodeBase32(s string) ([16]byte, error) {
+	var b [16]byte
+	if len(s) != base32Len {
+		return b, fmt.Errorf("%w: base32 length %d, want %d", ErrInvalidID, len(s), base32Len)
+	}
+	if crockfordDec[s[0]] > 7 {
+		// 第一个字符只承载 3 位，超过 '7' 会溢出 128 位
+		return b, fmt.Errorf("%w: %q overflows 128 bits", ErrInvalidID, s)
+	}
+	var acc uint16
+	bits := 0
+	j := 15
+	for i := base32Len - 1; i >= 0; i-- {
+		v := crockfordDec[s[i]]
+		if v < 0 {
+			return b, fmt.Errorf("%w: bad base32 character %q", ErrInvalidID, s[i])
+		}
+		acc |= uint16(v) << bits
+		bits += 5
+		if bits >= 8 && j >= 0 {
+			b[j] = byte(acc)
+			j--
+			acc >>= 8
+			bits -= 8
+		}
+	}
+	return b, nil
+}
+
+// encodeBase62 把 128 位数据编码为 22 个 base62 字符，不足时左侧补 '0'
+func encodeBase62(b [16]byte) string {
+	var out [base62Len]byte
+	// 按 32 位一组做长除法
+	n := [4]uint32{
+		uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]),
+		uint32(b[4])<<24 | uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7]),
+		uint32(b[8])<<24 | uint32(b[9])<<16 | uint32(b[10])<<8 | uint32(b[11]),
+		uint32(b[12])<<24 | uint32(b[13])<<16 | uint32(b[14])<<8 | uint32(b[15]),
+	}
+	for j := base62Len - 1; j >= 0; j-- {
+		var rem uint64
+		for i := range n {
+			cur := rem<<32 | uint64(n[i])
+			n[i] = uint32(cur / 62)
+			rem = cur % 62
+		}
+		out[j] = base62[rem]
+	}
+	return string(out[:])
+}
+
+// decodeBase62 解析 encodeBase62 的结果
+func decodeBase62(s string) ([16]byte, error) {
+	var b [16]byte
+	if len(s) != base62Len {
+		return b, fmt.Errorf("%w: base62 length %d, want %d", ErrInvalidID, len(s), base62Len)
+	}
+	var n [4]uint32
+	for i := 0; i < len(s); i++ {
+		v := base62Dec[s[i]]
+		if v < 0 {
+			return b, fmt.Errorf("%w: bad base62 character %q", ErrInvalidID, s[i])
+		}
+		// n = n*62 + v
+		carry := uint64(v)
+		for k := len(n) - 1; k >= 0; k-- {
+			cur := uint64(n[k])*62 + carry
+			n[k] = uint32(cur)
+			carry = cur >> 32
+		}
+		if carry != 0 {
+			return b, fmt.Errorf("%w: %q overflows 128 bits", ErrInvalidID, s)
+		}
+	}
+	for i, w := range n {
+		b[i*4] = byte(w >> 24)
+		b[i*4+1] = byte(w >> 16)
+		b[i*4+2] = byte(w >> 8)
+		b[i*4+3] = byte(w)
+	}
+	return b, nil
+}
@@ -0,0 +1,274 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 对外可见的一些错误
+var (
+	ErrNoWorkerAvailable = errors.New("id: no worker id available")
+	ErrLeaseLost         = errors.New("id: worker id lease lost")
+)
+
+// WorkerStore 租用 worker 编号的后端，同一时刻一个编号最多被一个 owner 持有
+type WorkerStore interface {
+	// Acquire 在 [0, max) 中租用一个空闲编号，没有空闲编号时返回 ErrNoWorkerAvailable
+	Acquire(ctx context.Context, owner string, max int64, ttl time.Duration) (int64, error)
+	// Renew 延长租约，编号已不属于 owner 时返回 false
+	Renew(ctx context.Context, worker int64, owner string, ttl time.Duration) (bool, error)
+	// Release 归还编号，编号已不属于 owner 时什么也不做
+	Release(ctx context.Context, worker int64, owner string) error
+}
+
+// LeaseOptions 控制租约行为
+type LeaseOptions struct {
+	TTL           time.Duration // 租约有效期
+	RenewInterval time.Duration // 续约间隔（< TTL，默认 TTL/3）
+	MaxWorkers    int64         // 可租用的编号个数，应等于 2^Layout.WorkerBits（默认 1024）
+	Owner         string        // 租约持有者标识（默认随机生成）
+}
+
+// 一些默认值
+const (
+	defaultLeaseTTL   = 30 * time.Second
+	defaultMaxWorkers = 1 << 10
+)
+
+// DefaultLeaseOptions 默认配置
+func DefaultLeaseOptions() LeaseOptions {
+	return LeaseOptions{
+		TTL:        defaultLeaseTTL,
+		MaxWorkers: defaultMaxWorkers,
+	}
+}
+
+// LeaseOption 函数式编程
+type LeaseOption func(*LeaseOptions)
+
+// WithLeaseTTL 初始化 TTL
+func WithLeaseTTL(ttl time.Duration) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.TTL = ttl
+	}
+}
+
+// WithLeaseRenewInterval 初始化 RenewInterval
+func WithLeaseRenewInterval(d time.Duration) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.RenewInterval = d
+	}
+}
+
+// WithMaxWorkers 初始化 MaxWorkers
+func WithMaxWorkers(n int64) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.MaxWorkers = n
+	}
+}
+
+// WithOwner 初始化 Owner
+func WithOwner(owner string) LeaseOption {
+	return func(o *LeaseOptions) {
+		o.Owner = owner
+	}
+}
+
+// Lease 一个 worker 编号的租约，后台协程按 RenewInterval 续约。
+// 续约被拒绝，或者距离上次成功续约已超过 TTL 时租约失效，之后必须停止使用该编号生成 ID：
+// 此时编号可能已经被其他进程租走。
+type Lease struct {
+	store  WorkerStore
+	worker int64
+	owner  string
+	ttl    time.Duration
+
+	validUntil atomic.Int64 // 租约确定有效的截止时间（UnixNano）
+	lost       chan struct{}
+	lostOnce   sync.Once
+
+	cancel context.CancelFunc // 用于停止续约协程
+	done   chan struct{}
+}
+
+// AcquireWorker 从 store 租用一个 worker 编号，并启动续约协程
+func AcquireWorker(ctx context.Context, store WorkerStore, opts ...LeaseOption) (*Lease, error) {
+	// 默认配置
+	cfg := DefaultLeaseOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	// base case
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultLeaseTTL
+	}
+	if cfg.RenewInterval <= 0 || cfg.RenewInterval >= cfg.TTL {
+		cfg.RenewInterval = cfg.TTL / 3
+	}
+	if cfg.MaxWorkers <= 0 {
+		cfg.MaxWorkers = defaultMaxWorkers
+	}
+	if cfg.Owner == "" {
+		cfg.Owner = uuid.NewString()
+	}
+
+	// 以发起请求的时间计算有效期，保守一些
+	start := time.Now()
+	worker, err := store.Acquire(ctx, cfg.Owner, cfg.MaxWorkers, cfg.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	wctx, cancel := context.WithCancel(context.Background())
+	l := &Lease{
+		store:  store,
+		worker: worker,
+		owner:  cfg.Owner,
+		ttl:    cfg.TTL,
+		lost:   make(chan struct{}),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	l.validUntil.Store(start.Add(cfg.TTL).UnixNano())
+	go l.watchdog(wctx, cfg.RenewInterval)
+	return l, nil
+}
+
+// Worker 返回租到的编号
+func (l *Lease) Worker() int64 { return l.worker }
+
+// Owner 返回租约持有者标识
+func (l *Lease) Owner() string { return l.owner }
+
+// Done 租约失效后关闭
+func (l *Lease) Done() <-chan struct{} { return l.lost }
+
+// Valid 租约当前是否仍然有效
+func (l *Lease) Valid() bool {
+	select {
+	case <-l.lost:
+		return false
+	default:
+	}
+	if time.Now().UnixNano() >= l.validUntil.Load() {
+		l.markLost()
+		return false
+	}
+	return true
+}
+
+// Release 停止续约并归还编号，之后租约失效
+func (l *Lease) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+	l.markLost()
+	return l.store.Release(ctx, l.worker, l.owner)
+}
+
+// markLost 标记租约失效
+func (l *Lease) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// watchdog 定期续约，直到 ctx 被取消或租约失效。
+// 续约出错（例如网络抖动）时继续重试，只要在有效期内续约成功租约就不会失效。
+func (l *Lease) watchdog(ctx context.Context, interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.lost:
+			return
+		case <-ticker.C:
+			start := time.Now()
+			ok, err := l.store.Renew(ctx, l.worker, l.owner, l.ttl)
+			switch {
+			case err != nil:
+				// 只检查是否已经超过有效期
+				l.Valid()
+			case !ok:
+				l.markLost()
+				return
+			default:
+				l.validUntil.Store(start.Add(l.ttl).UnixNano())
+			}
+		}
+	}
+}
+
+// WithLease 使用租约提供的 worker 编号；租约失效后 Next 返回 ErrLeaseLost
+func WithLease(l *Lease) Option {
+	return func(o *Options) {
+		o.Worker = l.Worker()
+		o.lease = l
+	}
+}
+
+// MemoryWorkerStore 进程内的 WorkerStore，用于测试和单机场景
+type MemoryWorkerStore struct {
+	mu     sync.Mutex
+	leases map[int64]memoryLease
+}
+
+// memoryLease 一个编号的持有者与过期时间
+type memoryLease struct {
+	owner    string
+	expireAt time.Time
+}
+
+// NewMemoryWorkerStore 创建进程内的 WorkerStore
+func NewMemoryWorkerStore() *MemoryWorkerStore {
+	return &MemoryWorkerStore{leases: make(map[int64]memoryLease)}
+}
+
+// Acquire 实现 WorkerStore
+func (s *MemoryWorkerStore) Acquire(_ context.Context, owner string, max int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for w := int64(0); w < max; w++ {
+		if l, ok := s.leases[w]; ok && now.Before(l.expireAt) {
+			continue
+		}
+		s.leases[w] = memoryLease{owner: owner, expireAt: now.Add(ttl)}
+		return w, nil
+	}
+	return 0, fmt.Errorf("%w: all %d ids are leased", ErrNoWorkerAvailable, max)
+}
+
+// Renew 实现 WorkerStore
+func (s *MemoryWorkerStore) Renew(_ context.Context, worker int64, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	l, ok := s.leases[worker]
+	if !ok || l.owner != owner || !now.Before(l.expireAt) {
+		return false, nil
+	}
+	s.leases[worker] = memoryLease{owner: owner, expireAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Release 实现 WorkerStore
+func (s *MemoryWorkerStore) Release(_ context.Context, worker int64, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.leases[worker]; ok && l.owner == owner {
+		delete(s.leases, worker)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package id
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 默认的 key 前缀；花括号是 Redis Cluster 的 hash tag，保证所有编号落在同一个 slot，
+// 这样获取脚本可以在一次调用里检查全部编号
+const defaultWorkerPrefix = "{gopulse:id}:worker:"
+
+// RedisWorkerStore 基于 Redis 的 WorkerStore，每个编号对应一个 key，value 为持有者
+type RedisWorkerStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// NewRedisWorkerStore 创建 Redis 后端，prefix 为空时使用默认前缀
+func NewRedisWorkerStore(rdb *redis.Client, prefix string) *RedisWorkerStore {
+	if prefix == "" {
+		prefix = defaultWorkerPrefix
+	}
+	return &RedisWorkerStore{rdb: rdb, prefix: prefix}
+}
+
+// lua 脚本：按编号顺序找到第一个空闲 key 并写入持有者，没有空闲编号返回 -1
+var acquireWorkerScript = redis.NewScript(`
+for i = 0, tonumber(ARGV[2]) - 1 do
+  if redis.call("SET", ARGV[1] .. i, ARGV[3], "NX", "PX", ARGV[4]) then
+    return i
+  end
+end
+return -1
+`)
+
+// lua 脚本：续约（只有持有者匹配时才 PEXPIRE）
+var renewWorkerScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+else
+  return 0
+end
+`)
+
+// lua 脚本：释放（只有持有者匹配时才删除）
+var releaseWorkerScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+else
+  return 0
+end
+`)
+
+// key 编号对应的 Redis key
+func (s *RedisWorkerStore) key(worker int64) string {
+	return s.prefix + strconv.FormatInt(worker, 10)
+}
+
+// Acquire 实现 WorkerStore
+func (s *RedisWorkerStore) Acquire(ctx context.Context, owner string, max int64, ttl time.Duration) (int64, error) {
+	// KEYS[1] 只用于路由，脚本实际访问的 key 都带同一个 hash tag
+	w, err := acquireWorkerScript.Run(ctx, s.rdb, []string{s.key(0)}, s.prefix, max, owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if w < 0 {
+		return 0, fmt.Errorf("%w: all %d ids are leased", ErrNoWorkerAvailable, max)
+	}
+	return w, nil
+}
+
+// Renew 实现 WorkerStore
+func (s *RedisWorkerStore) Renew(ctx context.Context, worker int64, owner string, ttl time.Duration) (bool, error) {
+	res, err := renewWorkerScript.Run(ctx, s.rdb, []string{s.key(worker)}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// Release 实现 WorkerStore
+func (s *RedisWorkerStore) Release(ctx context.Context, worker int64, owner string) error {
+	return releaseWorkerScript.Run(ctx, s.rdb, []string{s.key(worker)}, owner).Err()
+}
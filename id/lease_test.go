@@ -0,0 +1,127 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 不同持有者租到不同编号，编号用完时返回 ErrNoWorkerAvailable，归还后可以再次租用
+func testWorkerStore(t *testing.T, store WorkerStore) {
+	ctx := context.Background()
+	a, err := AcquireWorker(ctx, store, WithMaxWorkers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := AcquireWorker(ctx, store, WithMaxWorkers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Worker() == b.Worker() {
+		t.Fatalf("two leases got the same worker %d", a.Worker())
+	}
+	if _, err := AcquireWorker(ctx, store, WithMaxWorkers(2)); !errors.Is(err, ErrNoWorkerAvailable) {
+		t.Fatalf("expected ErrNoWorkerAvailable, got %v", err)
+	}
+
+	// 他人无法续约或释放
+	if ok, _ := store.Renew(ctx, a.Worker(), "someone-else", time.Second); ok {
+		t.Fatal("renew by another owner should fail")
+	}
+
+	if err := a.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if a.Valid() {
+		t.Fatal("released lease should be invalid")
+	}
+	c, err := AcquireWorker(ctx, store, WithMaxWorkers(2))
+	if err != nil || c.Worker() != a.Worker() {
+		t.Fatalf("expected to reuse worker %d, got %v, %v", a.Worker(), c, err)
+	}
+	b.Release(ctx)
+	c.Release(ctx)
+}
+
+func TestMemoryWorkerStore(t *testing.T) {
+	testWorkerStore(t, NewMemoryWorkerStore())
+}
+
+func TestRedisWorkerStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	testWorkerStore(t, NewRedisWorkerStore(rdb, ""))
+}
+
+// 后台续约使租约在超过 TTL 后依然有效
+func TestLeaseRenew(t *testing.T) {
+	lease, err := AcquireWorker(context.Background(), NewMemoryWorkerStore(),
+		WithLeaseTTL(60*time.Millisecond), WithLeaseRenewInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release(context.Background())
+
+	time.Sleep(200 * time.Millisecond)
+	if !lease.Valid() {
+		t.Fatal("lease should still be valid after renewals")
+	}
+}
+
+// 编号被他人抢走后租约失效，Snowflake 停止生成
+func TestLeaseLostHaltsSnowflake(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	store := NewRedisWorkerStore(rdb, "test:worker:")
+
+	lease, err := AcquireWorker(context.Background(), store,
+		WithLeaseTTL(time.Second), WithLeaseRenewInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSnowflake(WithLease(lease))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟租约过期后被其他进程租走
+	mr.Set("test:worker:0", "intruder")
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lease should be lost")
+	}
+	if _, err := s.Next(); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+}
+
+// 续约一直失败时，超过 TTL 租约自动失效
+func TestLeaseExpiresWithoutRenewal(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	lease, err := AcquireWorker(context.Background(), NewRedisWorkerStore(rdb, ""),
+		WithLeaseTTL(50*time.Millisecond), WithLeaseRenewInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Redis 不可用，续约全部出错
+	mr.Close()
+
+	time.Sleep(80 * time.Millisecond)
+	if lease.Valid() {
+		t.Fatal("lease should expire when renewals keep failing")
+	}
+}
@@ -0,0 +1,118 @@
+package id
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+)
+
+// GeneratorOptions 控制 ULID、UUIDv7 生成器行为
+type GeneratorOptions struct {
+	Monotonic bool      // 同一毫秒内递增随机部分，保证严格有序（默认开启）
+	Entropy   io.Reader // 随机数来源（默认 crypto/rand）
+}
+
+// DefaultGeneratorOptions 默认配置
+func DefaultGeneratorOptions() GeneratorOptions {
+	return GeneratorOptions{
+		Monotonic: true,
+		Entropy:   rand.Reader,
+	}
+}
+
+// GeneratorOption 函数式编程
+type GeneratorOption func(*GeneratorOptions)
+
+// WithMonotonic 初始化 Monotonic
+func WithMonotonic(enable bool) GeneratorOption {
+	return func(o *GeneratorOptions) {
+		o.Monotonic = enable
+	}
+}
+
+// WithEntropy 初始化 Entropy
+func WithEntropy(r io.Reader) GeneratorOption {
+	return func(o *GeneratorOptions) {
+		o.Entropy = r
+	}
+}
+
+// randomGen ULID 与 UUIDv7 共用的生成逻辑：48 位毫秒时间戳 + hi、lo 两段随机数。
+// 单调模式下同一毫秒（或时钟回拨）时把 (hi, lo) 当作一个整数加 1，
+// 溢出时借用下一毫秒，因此生成的序列总是严格递增。
+type randomGen struct {
+	cfg    GeneratorOptions
+	now    func() time.Time // 测试时替换时钟
+	hiMask uint16
+	loMask uint64
+
+	mu     sync.Mutex
+	ms     int64
+	hi     uint16
+	lo     uint64
+	buf    [10]byte
+	primed bool // 是否已经生成过
+}
+
+// newRandomGen 创建生成器，hiBits、loBits 为两段随机数的位数
+func newRandomGen(hiBits, loBits uint, opts []GeneratorOption) *randomGen {
+	// 默认配置
+	cfg := DefaultGeneratorOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	if cfg.Entropy == nil {
+		cfg.Entropy = rand.Reader
+	}
+	return &randomGen{
+		cfg:    cfg,
+		now:    time.Now,
+		hiMask: uint16(1<<hiBits - 1),
+		loMask: uint64(1)<<loBits - 1,
+	}
+}
+
+// next 返回下一组 (毫秒时间戳, hi, lo)
+func (g *randomGen) next() (int64, uint16, uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.now().UnixMilli()
+	if g.cfg.Monotonic && g.primed && ms <= g.ms {
+		g.lo = (g.lo + 1) & g.loMask
+		if g.lo == 0 {
+			g.hi = (g.hi + 1) & g.hiMask
+			if g.hi == 0 {
+				g.ms++
+			}
+		}
+		return g.ms, g.hi, g.lo, nil
+	}
+
+	if _, err := io.ReadFull(g.cfg.Entropy, g.buf[:]); err != nil {
+		return 0, 0, 0, err
+	}
+	g.ms = ms
+	g.hi = binary.BigEndian.Uint16(g.buf[:2]) & g.hiMask
+	g.lo = binary.BigEndian.Uint64(g.buf[2:]) & g.loMask
+	g.primed = true
+	return g.ms, g.hi, g.lo, nil
+}
+
+// putMillis 把 48 位毫秒时间戳写入 b 的前 6 个字节
+func putMillis(b []byte, ms int64) {
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+}
+
+// millis 读取 b 前 6 个字节中的毫秒时间戳
+func millis(b []byte) int64 {
+	return int64(b[0])<<40 | int64(b[1])<<32 | int64(b[2])<<24 |
+		int64(b[3])<<16 | int64(b[4])<<8 | int64(b[5])
+}
@@ -0,0 +1,242 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnknownTag 存储中没有这个业务标识
+var ErrUnknownTag = errors.New("id: unknown segment tag")
+
+// SegmentStore 号段存储：每次把 tag 的当前最大值增加 step，并返回增加后的值。
+// 调用方独占号段 (max-step, max]。
+type SegmentStore interface {
+	Next(ctx context.Context, tag string, step int64) (max int64, err error)
+}
+
+// SegmentOptions 控制号段分配行为
+type SegmentOptions struct {
+	Step              int64         // 初始号段长度（默认 1000）
+	MinStep           int64         // 自适应调整的下限（默认等于 Step）
+	MaxStep           int64         // 自适应调整的上限（默认 Step 的 100 倍）
+	PrefetchThreshold float64       // 当前号段消耗超过该比例时异步预取下一个号段（默认 0.1）
+	TargetDuration    time.Duration // 期望一个号段使用多久，据此调整号段长度（0 表示固定长度）
+	FetchTimeout      time.Duration // 后台预取单次请求的超时（默认 5s）
+}
+
+// 一些默认值
+const (
+	defaultStep              = 1000
+	defaultPrefetchThreshold = 0.1
+	defaultTargetDuration    = 15 * time.Minute
+	defaultFetchTimeout      = 5 * time.Second
+)
+
+// DefaultSegmentOptions 默认配置
+func DefaultSegmentOptions() SegmentOptions {
+	return SegmentOptions{
+		Step:              defaultStep,
+		PrefetchThreshold: defaultPrefetchThreshold,
+		TargetDuration:    defaultTargetDuration,
+		FetchTimeout:      defaultFetchTimeout,
+	}
+}
+
+// SegmentOption 函数式编程
+type SegmentOption func(*SegmentOptions)
+
+// WithStep 初始化 Step，以及自适应调整的上下限
+func WithStep(step, minStep, maxStep int64) SegmentOption {
+	return func(o *SegmentOptions) {
+		o.Step = step
+		o.MinStep = minStep
+		o.MaxStep = maxStep
+	}
+}
+
+// WithPrefetchThreshold 初始化 PrefetchThreshold
+func WithPrefetchThreshold(ratio float64) SegmentOption {
+	return func(o *SegmentOptions) {
+		o.PrefetchThreshold = ratio
+	}
+}
+
+// WithTargetDuration 初始化 TargetDuration
+func WithTargetDuration(d time.Duration) SegmentOption {
+	return func(o *SegmentOptions) {
+		o.TargetDuration = d
+	}
+}
+
+// WithFetchTimeout 初始化 FetchTimeout
+func WithFetchTimeout(d time.Duration) SegmentOption {
+	return func(o *SegmentOptions) {
+		o.FetchTimeout = d
+	}
+}
+
+// segment 一个号段 [next, max]
+type segment struct {
+	next, max, step int64
+}
+
+// remaining 剩余可分配的 ID 个数
+func (s *segment) remaining() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.max - s.next + 1
+}
+
+// fetchCall 一次进行中的号段请求
+type fetchCall struct {
+	done chan struct{}
+	err  error
+}
+
+// SegmentAllocator Leaf 风格的号段分配器，并发安全。
+// 每次从 SegmentStore 取一段连续 ID 在内存中分配，消耗超过 PrefetchThreshold 后在后台预取下一段（双 buffer），
+// 因此大部分 Next 调用不需要访问存储。同一进程内分配的 ID 严格递增、号段内连续；
+// 进程退出时未用完的号段会留下空洞，多个进程之间的 ID 交错分配。
+type SegmentAllocator struct {
+	store SegmentStore
+	tag   string
+	cfg   SegmentOptions
+
+	mu        sync.Mutex
+	cur, buf  *segment
+	fetching  *fetchCall
+	step      int64
+	lastFetch time.Time
+}
+
+// NewSegmentAllocator 创建号段分配器，tag 区分不同的业务序列
+func NewSegmentAllocator(store SegmentStore, tag string, opts ...SegmentOption) *SegmentAllocator {
+	// 默认配置
+	cfg := DefaultSegmentOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	// base case
+	if cfg.Step <= 0 {
+		cfg.Step = defaultStep
+	}
+	if cfg.MinStep <= 0 || cfg.MinStep > cfg.Step {
+		cfg.MinStep = cfg.Step
+	}
+	if cfg.MaxStep < cfg.Step {
+		cfg.MaxStep = cfg.Step * 100
+	}
+	if cfg.PrefetchThreshold <= 0 || cfg.PrefetchThreshold >= 1 {
+		cfg.PrefetchThreshold = defaultPrefetchThreshold
+	}
+	if cfg.FetchTimeout <= 0 {
+		cfg.FetchTimeout = defaultFetchTimeout
+	}
+	return &SegmentAllocator{store: store, tag: tag, cfg: cfg, step: cfg.Step}
+}
+
+// Next 分配下一个 ID。当前号段用完且预取还没完成时会等待，直到拿到新号段、请求失败或 ctx 取消。
+func (a *SegmentAllocator) Next(ctx context.Context) (int64, error) {
+	a.mu.Lock()
+	for {
+		if a.cur.remaining() > 0 {
+			id := a.cur.next
+			a.cur.next++
+			a.maybePrefetchLocked()
+			a.mu.Unlock()
+			return id, nil
+		}
+		if a.buf != nil {
+			// 切换到预取好的号段
+			a.cur, a.buf = a.buf, nil
+			continue
+		}
+
+		call := a.fetchLocked()
+		a.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-call.done:
+		}
+		a.mu.Lock()
+		if call.err != nil && a.cur.remaining() == 0 && a.buf == nil {
+			a.mu.Unlock()
+			return 0, call.err
+		}
+	}
+}
+
+// Step 返回当前的号段长度
+func (a *SegmentAllocator) Step() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.step
+}
+
+// maybePrefetchLocked 当前号段消耗超过阈值且没有备用号段时，在后台预取，调用方需持有锁
+func (a *SegmentAllocator) maybePrefetchLocked() {
+	if a.buf != nil || a.fetching != nil {
+		return
+	}
+	used := a.cur.step - a.cur.remaining()
+	if float64(used) >= a.cfg.PrefetchThreshold*float64(a.cur.step) {
+		a.fetchLocked()
+	}
+}
+
+// fetchLocked 发起（或复用进行中的）号段请求，调用方需持有锁
+func (a *SegmentAllocator) fetchLocked() *fetchCall {
+	if a.fetching != nil {
+		return a.fetching
+	}
+	call := &fetchCall{done: make(chan struct{})}
+	a.fetching = call
+	step := a.nextStepLocked()
+	go a.fetch(call, step)
+	return call
+}
+
+// fetch 向存储申请号段，结果放入当前号段或备用号段
+func (a *SegmentAllocator) fetch(call *fetchCall, step int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.FetchTimeout)
+	defer cancel()
+	last, err := a.store.Next(ctx, a.tag, step)
+	if err != nil {
+		err = fmt.Errorf("id: fetch segment %q: %w", a.tag, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		seg := &segment{next: last - step + 1, max: last, step: step}
+		if a.cur.remaining() == 0 {
+			a.cur = seg
+		} else {
+			a.buf = seg
+		}
+	}
+	call.err = err
+	a.fetching = nil
+	close(call.done)
+}
+
+// nextStepLocked 根据两次取号段的间隔调整号段长度：
+// 一个号段用得比 TargetDuration 快就翻倍，慢于两倍 TargetDuration 就减半
+func (a *SegmentAllocator) nextStepLocked() int64 {
+	now := time.Now()
+	if a.cfg.TargetDuration > 0 && !a.lastFetch.IsZero() {
+		switch elapsed := now.Sub(a.lastFetch); {
+		case elapsed < a.cfg.TargetDuration:
+			a.step = min(a.step*2, a.cfg.MaxStep)
+		case elapsed > 2*a.cfg.TargetDuration:
+			a.step = max(a.step/2, a.cfg.MinStep)
+		}
+	}
+	a.lastFetch = now
+	return a.step
+}
@@ -0,0 +1,104 @@
+package id
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SegmentSchema SQLSegmentStore 使用的表结构（SQLite / MySQL / PostgreSQL 通用），表名可以修改
+const SegmentSchema = `CREATE TABLE IF NOT EXISTS id_segments (
+	biz_tag VARCHAR(128) NOT NULL PRIMARY KEY,
+	max_id  BIGINT       NOT NULL DEFAULT 0
+)`
+
+// Placeholder SQL 占位符风格
+type Placeholder int
+
+// 支持的占位符风格
+const (
+	Question Placeholder = iota // ?，SQLite、MySQL
+	Dollar                      // $1，PostgreSQL
+)
+
+// SQLSegmentStore 基于数据库表的 SegmentStore，每个 tag 一行。
+// 在一个事务里执行 UPDATE max_id = max_id + step 再读回 max_id，行锁保证多个实例拿到的号段不重叠。
+type SQLSegmentStore struct {
+	db     *sql.DB
+	update string
+	query  string
+	insert string
+}
+
+// NewSQLSegmentStore 创建 SQL 后端，table 为空时使用 id_segments
+func NewSQLSegmentStore(db *sql.DB, table string, ph Placeholder) *SQLSegmentStore {
+	if table == "" {
+		table = "id_segments"
+	}
+	p := func(i int) string {
+		if ph == Dollar {
+			return fmt.Sprintf("$%d", i)
+		}
+		return "?"
+	}
+	return &SQLSegmentStore{
+		db:     db,
+		update: fmt.Sprintf("UPDATE %s SET max_id = max_id + %s WHERE biz_tag = %s", table, p(1), p(2)),
+		query:  fmt.Sprintf("SELECT max_id FROM %s WHERE biz_tag = %s", table, p(1)),
+		insert: fmt.Sprintf("INSERT INTO %s (biz_tag, max_id) SELECT %s, %s WHERE NOT EXISTS (SELECT 1 FROM %s WHERE biz_tag = %s)",
+			table, p(1), p(2), table, p(3)),
+	}
+}
+
+// EnsureTag 在 tag 不存在时插入一行，start 为起始的 max_id（第一个 ID 为 start+1）
+func (s *SQLSegmentStore) EnsureTag(ctx context.Context, tag string, start int64) error {
+	_, err := s.db.ExecContext(ctx, s.insert, tag, start, tag)
+	return err
+}
+
+// Next 实现 SegmentStore，tag 不存在时返回 ErrUnknownTag
+func (s *SQLSegmentStore) Next(ctx context.Context, tag string, step int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, s.update, step, tag)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, err
+	} else if n == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownTag, tag)
+	}
+
+	var last int64
+	if err := tx.QueryRowContext(ctx, s.query, tag).Scan(&last); err != nil {
+		return 0, err
+	}
+	return last, tx.Commit()
+}
+
+// RedisSegmentStore 基于 Redis INCRBY 的 SegmentStore，tag 不存在时从 0 开始。
+// Redis 需要开启持久化，否则重启后会重新从 0 分配。
+type RedisSegmentStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// NewRedisSegmentStore 创建 Redis 后端，prefix 为空时使用 gopulse:id:segment:
+func NewRedisSegmentStore(rdb *redis.Client, prefix string) *RedisSegmentStore {
+	if prefix == "" {
+		prefix = "gopulse:id:segment:"
+	}
+	return &RedisSegmentStore{rdb: rdb, prefix: prefix}
+}
+
+// Next 实现 SegmentStore
+func (s *RedisSegmentStore) Next(ctx context.Context, tag string, step int64) (int64, error) {
+	return s.rdb.IncrBy(ctx, s.prefix+tag, step).Result()
+}
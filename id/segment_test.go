@@ -0,0 +1,167 @@
+package id
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	_ "modernc.org/sqlite"
+)
+
+// newSQLiteStore 在临时目录创建 SQLite 数据库并建表
+func newSQLiteStore(t *testing.T) *SQLSegmentStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "id.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(SegmentSchema); err != nil {
+		t.Fatal(err)
+	}
+	return NewSQLSegmentStore(db, "", Question)
+}
+
+// 多个分配器（模拟多个实例）并发取号：全局不重复，单个分配器内严格递增，且号段内连续
+func testSegmentAllocators(t *testing.T, store SegmentStore) {
+	const allocators, per = 4, 2000
+	var mu sync.Mutex
+	var all []int64
+	var wg sync.WaitGroup
+	for i := 0; i < allocators; i++ {
+		a := NewSegmentAllocator(store, "order", WithStep(100, 100, 1000))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]int64, 0, per)
+			for j := 0; j < per; j++ {
+				id, err := a.Next(context.Background())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(ids) > 0 && id <= ids[len(ids)-1] {
+					t.Errorf("not increasing: %d after %d", id, ids[len(ids)-1])
+					return
+				}
+				ids = append(ids, id)
+			}
+			mu.Lock()
+			all = append(all, ids...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	for i := 1; i < len(all); i++ {
+		if all[i] == all[i-1] {
+			t.Fatalf("duplicate id %d", all[i])
+		}
+	}
+	if all[0] != 1 {
+		t.Fatalf("first id = %d, want 1", all[0])
+	}
+}
+
+func TestSQLSegmentStore(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+	if _, err := store.Next(ctx, "order", 10); !errors.Is(err, ErrUnknownTag) {
+		t.Fatalf("expected ErrUnknownTag, got %v", err)
+	}
+	if err := store.EnsureTag(ctx, "order", 0); err != nil {
+		t.Fatal(err)
+	}
+	// 重复调用不会重置
+	if err := store.EnsureTag(ctx, "order", 0); err != nil {
+		t.Fatal(err)
+	}
+	testSegmentAllocators(t, store)
+}
+
+func TestRedisSegmentStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	testSegmentAllocators(t, NewRedisSegmentStore(rdb, ""))
+}
+
+// countingStore 统计请求次数，可以注入延迟与错误
+type countingStore struct {
+	mu    sync.Mutex
+	max   int64
+	steps []int64
+	delay time.Duration
+	fail  atomic.Bool
+}
+
+func (s *countingStore) Next(ctx context.Context, _ string, step int64) (int64, error) {
+	time.Sleep(s.delay)
+	if s.fail.Load() {
+		return 0, errors.New("store down")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.max += step
+	s.steps = append(s.steps, step)
+	return s.max, nil
+}
+
+// 消耗超过阈值后后台预取，切换号段时不需要等待；ID 连续
+func TestSegmentPrefetch(t *testing.T) {
+	store := &countingStore{delay: 5 * time.Millisecond}
+	a := NewSegmentAllocator(store, "t", WithStep(10, 10, 10), WithPrefetchThreshold(0.5))
+	ctx := context.Background()
+
+	for want := int64(1); want <= 6; want++ {
+		id, err := a.Next(ctx)
+		if err != nil || id != want {
+			t.Fatalf("Next = %d, %v, want %d", id, err, want)
+		}
+	}
+	// 等待预取完成，之后跨号段取号不会访问存储
+	time.Sleep(20 * time.Millisecond)
+	store.fail.Store(true)
+	for want := int64(7); want <= 20; want++ {
+		id, err := a.Next(ctx)
+		if err != nil || id != want {
+			t.Fatalf("Next = %d, %v, want %d", id, err, want)
+		}
+	}
+	// 两个号段都用完，存储不可用时返回错误
+	if _, err := a.Next(ctx); err == nil {
+		t.Fatal("expected error when the store is down")
+	}
+	store.fail.Store(false)
+	if id, err := a.Next(ctx); err != nil || id != 21 {
+		t.Fatalf("Next after recovery = %d, %v", id, err)
+	}
+}
+
+// 号段消耗很快时号段长度翻倍，不超过上限
+func TestSegmentAdaptiveStep(t *testing.T) {
+	store := &countingStore{}
+	a := NewSegmentAllocator(store, "t", WithStep(10, 10, 80), WithTargetDuration(time.Hour))
+	for i := 0; i < 500; i++ {
+		if _, err := a.Next(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.Step() != 80 {
+		t.Fatalf("step = %d, want 80", a.Step())
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.steps[0] != 10 || store.steps[1] != 20 || store.steps[2] != 40 {
+		t.Fatalf("unexpected steps %v", store.steps)
+	}
+}
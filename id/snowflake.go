@@ -0,0 +1,319 @@
+// Package id 提供分布式环境下按时间有序、不重复的 ID 生成器。
+package id
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 对外可见的一些错误
+var (
+	ErrClockRollback = errors.New("id: clock moved backwards")
+	ErrTimeOverflow  = errors.New("id: timestamp overflows the layout")
+	ErrInvalidLayout = errors.New("id: invalid bit layout")
+	ErrInvalidWorker = errors.New("id: worker id out of range")
+)
+
+// ClockRollbackError 时钟回拨且无法按策略处理时返回，errors.Is(err, ErrClockRollback) 为 true
+type ClockRollbackError struct {
+	Last  time.Time     // 上一次生成 ID 使用的时间
+	Now   time.Time     // 当前读到的时间
+	Drift time.Duration // 回拨的幅度
+}
+
+// Error 实现 error
+func (e *ClockRollbackError) Error() string {
+	return fmt.Sprintf("id: clock moved backwards by %s (last %s, now %s)",
+		e.Drift, e.Last.Format(time.RFC3339Nano), e.Now.Format(time.RFC3339Nano))
+}
+
+// Is 使 errors.Is(err, ErrClockRollback) 成立
+func (e *ClockRollbackError) Is(target error) bool {
+	return target == ErrClockRollback
+}
+
+// Layout 描述 63 位 ID 的位分配，从高到低依次为：
+//
+//	| 0 | 时间戳 | 回拨计数（预留位） | worker | 序列号 |
+//
+// 各段位数之和不能超过 63，最高位恒为 0，保证 ID 为正数。
+type Layout struct {
+	TimeBits     uint8 // 时间戳位数，按 Unit 计数
+	ReservedBits uint8 // 预留位，时钟回拨时以 RollbackBorrow 策略借用（0 表示不预留）
+	WorkerBits   uint8 // worker 编号位数
+	SequenceBits uint8 // 同一时间单位内的序列号位数
+}
+
+// DefaultLayout 经典 Snowflake 布局：41 位毫秒时间戳（约 69 年）、10 位 worker、12 位序列号
+var DefaultLayout = Layout{TimeBits: 41, WorkerBits: 10, SequenceBits: 12}
+
+// validate 检查位分配是否合法
+func (l Layout) validate() error {
+	total := int(l.TimeBits) + int(l.ReservedBits) + int(l.WorkerBits) + int(l.SequenceBits)
+	if l.TimeBits == 0 || l.SequenceBits == 0 || total > 63 {
+		return fmt.Errorf("%w: %d+%d+%d+%d bits", ErrInvalidLayout, l.TimeBits, l.ReservedBits, l.WorkerBits, l.SequenceBits)
+	}
+	return nil
+}
+
+// 各段的位移
+func (l Layout) workerShift() uint   { return uint(l.SequenceBits) }
+func (l Layout) reservedShift() uint { return uint(l.SequenceBits + l.WorkerBits) }
+func (l Layout) timeShift() uint     { return uint(l.SequenceBits + l.WorkerBits + l.ReservedBits) }
+
+// mask 返回 bits 位全 1 的掩码
+func mask(bits uint8) int64 { return 1<<bits - 1 }
+
+// Rollback 时钟回拨的处理策略
+type Rollback int
+
+// 内置的回拨处理策略
+const (
+	RollbackWait   Rollback = iota // 回拨幅度不超过 MaxWait 时等待时钟追上，否则返回 ClockRollbackError
+	RollbackBorrow                 // 递增预留位中的回拨计数后继续生成，要求 Layout.ReservedBits > 0
+	RollbackFail                   // 直接返回 ClockRollbackError
+)
+
+// String 返回策略名称，方便打印
+func (r Rollback) String() string {
+	switch r {
+	case RollbackWait:
+		return "wait"
+	case RollbackBorrow:
+		return "borrow"
+	case RollbackFail:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+// Options 控制 Snowflake 行为
+type Options struct {
+	Epoch    time.Time     // 时间戳的起点，一经上线不能再修改
+	Unit     time.Duration // 时间戳的单位（默认 1ms）
+	Layout   Layout        // 位分配（默认 DefaultLayout）
+	Worker   int64         // worker 编号，范围 [0, 2^WorkerBits)
+	Rollback Rollback      // 时钟回拨的处理策略（默认 RollbackWait）
+	MaxWait  time.Duration // RollbackWait 最多等待多久（默认 5ms）
+
+	// lease 保存 WithLease 传入的租约，租约失效后停止生成
+	lease *Lease
+}
+
+// 一些默认值
+const (
+	defaultUnit    = time.Millisecond
+	defaultMaxWait = 5 * time.Millisecond
+)
+
+// DefaultEpoch 默认的时间起点：2024-01-01 00:00:00 UTC
+var DefaultEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		Epoch:    DefaultEpoch,
+		Unit:     defaultUnit,
+		Layout:   DefaultLayout,
+		Rollback: RollbackWait,
+		MaxWait:  defaultMaxWait,
+	}
+}
+
+// Option 函数式编程
+type Option func(*Options)
+
+// WithEpoch 初始化 Epoch
+func WithEpoch(t time.Time) Option {
+	return func(o *Options) {
+		o.Epoch = t
+	}
+}
+
+// WithUnit 初始化 Unit
+func WithUnit(d time.Duration) Option {
+	return func(o *Options) {
+		o.Unit = d
+	}
+}
+
+// WithLayout 初始化 Layout
+func WithLayout(l Layout) Option {
+	return func(o *Options) {
+		o.Layout = l
+	}
+}
+
+// WithWorker 初始化 Worker
+func WithWorker(w int64) Option {
+	return func(o *Options) {
+		o.Worker = w
+	}
+}
+
+// WithRollback 初始化 Rollback 与 MaxWait（只对 RollbackWait 生效）
+func WithRollback(r Rollback, maxWait time.Duration) Option {
+	return func(o *Options) {
+		o.Rollback = r
+		o.MaxWait = maxWait
+	}
+}
+
+// Snowflake 并发安全的 Snowflake ID 生成器。
+// 同一时间单位内通过序列号区分，序列号用完时等待下一个时间单位；
+// 不同进程必须使用不同的 worker 编号。
+type Snowflake struct {
+	cfg Options
+	now func() time.Time // 测试时替换时钟
+
+	mu       sync.Mutex
+	last     int64 // 上一次使用的时间戳（单位：Unit，相对 Epoch）
+	sequence int64
+	reserved int64 // 当前回拨计数
+}
+
+// NewSnowflake 创建生成器，位分配或 worker 编号不合法时返回错误
+func NewSnowflake(opts ...Option) (*Snowflake, error) {
+	cfg, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Worker < 0 || cfg.Worker > mask(cfg.Layout.WorkerBits) {
+		return nil, fmt.Errorf("%w: %d does not fit in %d bits", ErrInvalidWorker, cfg.Worker, cfg.Layout.WorkerBits)
+	}
+	if cfg.Rollback == RollbackBorrow && cfg.Layout.ReservedBits == 0 {
+		return nil, fmt.Errorf("%w: RollbackBorrow requires ReservedBits > 0", ErrInvalidLayout)
+	}
+	return &Snowflake{cfg: cfg, now: time.Now, last: -1}, nil
+}
+
+// newOptions 合并配置并校验
+func newOptions(opts []Option) (Options, error) {
+	// 默认配置
+	cfg := DefaultOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	if cfg.Unit <= 0 {
+		cfg.Unit = defaultUnit
+	}
+	if err := cfg.Layout.validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// tick 返回 t 相对 Epoch 的时间戳
+func (s *Snowflake) tick(t time.Time) int64 {
+	return int64(t.Sub(s.cfg.Epoch) / s.cfg.Unit)
+}
+
+// Next 生成下一个 ID
+func (s *Snowflake) Next() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cfg.lease != nil && !s.cfg.lease.Valid() {
+		return 0, ErrLeaseLost
+	}
+
+	now := s.tick(s.now())
+	if now < 0 {
+		return 0, fmt.Errorf("%w: clock is before epoch %s", ErrTimeOverflow, s.cfg.Epoch.Format(time.RFC3339))
+	}
+	if now < s.last {
+		var err error
+		if now, err = s.rollbackLocked(now); err != nil {
+			return 0, err
+		}
+	}
+
+	if now == s.last {
+		s.sequence = (s.sequence + 1) & mask(s.cfg.Layout.SequenceBits)
+		if s.sequence == 0 {
+			// 当前时间单位的序列号用完，等到下一个时间单位
+			now = s.waitLocked(s.last + 1)
+		}
+	} else {
+		s.sequence = 0
+	}
+
+	if now > mask(s.cfg.Layout.TimeBits) {
+		return 0, fmt.Errorf("%w: %d units since epoch", ErrTimeOverflow, now)
+	}
+	s.last = now
+
+	l := s.cfg.Layout
+	return now<<l.timeShift() |
+		s.reserved<<l.reservedShift() |
+		s.cfg.Worker<<l.workerShift() |
+		s.sequence, nil
+}
+
+// rollbackLocked 按策略处理时钟回拨，返回处理后应使用的时间戳
+func (s *Snowflake) rollbackLocked(now int64) (int64, error) {
+	drift := time.Duration(s.last-now) * s.cfg.Unit
+	switch s.cfg.Rollback {
+	case RollbackWait:
+		if drift <= s.cfg.MaxWait {
+			return s.waitLocked(s.last), nil
+		}
+	case RollbackBorrow:
+		// 回拨计数变化后，(时间戳, 回拨计数) 组合与回拨前不会重复，直到计数绕回一圈
+		s.reserved = (s.reserved + 1) & mask(s.cfg.Layout.ReservedBits)
+		// 在新的回拨计数下重新开始，序列号从 0 计
+		s.last = -1
+		return now, nil
+	}
+	return 0, &ClockRollbackError{
+		Last:  s.cfg.Epoch.Add(time.Duration(s.last) * s.cfg.Unit),
+		Now:   s.cfg.Epoch.Add(time.Duration(now) * s.cfg.Unit),
+		Drift: drift,
+	}
+}
+
+// waitLocked 自旋等待直到时间戳不小于 target
+func (s *Snowflake) waitLocked(target int64) int64 {
+	for {
+		now := s.tick(s.now())
+		if now >= target {
+			return now
+		}
+		time.Sleep(time.Duration(target-now) * s.cfg.Unit / 2)
+	}
+}
+
+// Parts ID 拆解后的各个部分
+type Parts struct {
+	Time     time.Time // 生成时间（精度为 Unit）
+	Reserved int64     // 回拨计数
+	Worker   int64
+	Sequence int64
+}
+
+// Parse 按 opts 描述的布局与起点拆解 ID，opts 必须与生成时一致
+func Parse(id int64, opts ...Option) (Parts, error) {
+	cfg, err := newOptions(opts)
+	if err != nil {
+		return Parts{}, err
+	}
+	return parse(id, cfg), nil
+}
+
+// Parse 按生成器自身的配置拆解 ID
+func (s *Snowflake) Parse(id int64) Parts {
+	return parse(id, s.cfg)
+}
+
+func parse(id int64, cfg Options) Parts {
+	l := cfg.Layout
+	return Parts{
+		Time:     cfg.Epoch.Add(time.Duration(id>>l.timeShift()&mask(l.TimeBits)) * cfg.Unit),
+		Reserved: id >> l.reservedShift() & mask(l.ReservedBits),
+		Worker:   id >> l.workerShift() & mask(l.WorkerBits),
+		Sequence: id & mask(l.SequenceBits),
+	}
+}
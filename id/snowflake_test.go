@@ -0,0 +1,204 @@
+package id
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 可以手动拨动的时钟
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestSnowflake(t *testing.T, clock *fakeClock, opts ...Option) *Snowflake {
+	t.Helper()
+	s, err := NewSnowflake(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = clock.now
+	return s
+}
+
+// 并发生成的 ID 不重复，且单个协程内严格递增
+func TestSnowflakeUnique(t *testing.T) {
+	s, err := NewSnowflake(WithWorker(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, per = 8, 5000
+	var mu sync.Mutex
+	seen := make(map[int64]struct{}, workers*per)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last int64
+			ids := make([]int64, 0, per)
+			for j := 0; j < per; j++ {
+				id, err := s.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if id <= last {
+					t.Errorf("id not increasing: %d after %d", id, last)
+					return
+				}
+				last = id
+				ids = append(ids, id)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range ids {
+				if _, dup := seen[id]; dup {
+					t.Errorf("duplicate id %d", id)
+				}
+				seen[id] = struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// 自定义布局与起点下 Parse 能还原各个部分
+func TestSnowflakeParse(t *testing.T) {
+	epoch := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: epoch.Add(123 * 10 * time.Millisecond)}
+	opts := []Option{
+		WithEpoch(epoch),
+		WithUnit(10 * time.Millisecond),
+		WithLayout(Layout{TimeBits: 39, ReservedBits: 2, WorkerBits: 16, SequenceBits: 6}),
+		WithWorker(0xBEEF),
+	}
+	s := newTestSnowflake(t, clock, opts...)
+	s.Next()
+	id, _ := s.Next()
+
+	p, err := Parse(id, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Time.Equal(clock.now()) || p.Worker != 0xBEEF || p.Sequence != 1 || p.Reserved != 0 {
+		t.Fatalf("unexpected parts %+v", p)
+	}
+	if s.Parse(id) != p {
+		t.Fatal("method and function Parse disagree")
+	}
+}
+
+// 序列号用完时等待下一个时间单位
+func TestSnowflakeSequenceExhausted(t *testing.T) {
+	clock := &fakeClock{t: DefaultEpoch.Add(time.Hour)}
+	s := newTestSnowflake(t, clock, WithLayout(Layout{TimeBits: 41, WorkerBits: 10, SequenceBits: 2}))
+	for i := 0; i < 4; i++ {
+		s.Next()
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		clock.add(time.Millisecond)
+	}()
+	id, err := s.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := s.Parse(id); p.Sequence != 0 || !p.Time.Equal(DefaultEpoch.Add(time.Hour+time.Millisecond)) {
+		t.Fatalf("unexpected parts %+v", p)
+	}
+}
+
+// 三种时钟回拨策略
+func TestSnowflakeRollback(t *testing.T) {
+	start := DefaultEpoch.Add(time.Hour)
+
+	t.Run("fail", func(t *testing.T) {
+		clock := &fakeClock{t: start}
+		s := newTestSnowflake(t, clock, WithRollback(RollbackFail, 0))
+		s.Next()
+		clock.add(-3 * time.Millisecond)
+		_, err := s.Next()
+		var re *ClockRollbackError
+		if !errors.Is(err, ErrClockRollback) || !errors.As(err, &re) || re.Drift != 3*time.Millisecond {
+			t.Fatalf("expected ClockRollbackError, got %v", err)
+		}
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		clock := &fakeClock{t: start}
+		s := newTestSnowflake(t, clock, WithRollback(RollbackWait, 5*time.Millisecond))
+		first, _ := s.Next()
+		clock.add(-2 * time.Millisecond)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			clock.add(2 * time.Millisecond)
+		}()
+		id, err := s.Next()
+		if err != nil || id <= first {
+			t.Fatalf("Next = %d, %v (first %d)", id, err, first)
+		}
+
+		// 超过等待上限直接失败
+		clock.add(-time.Second)
+		if _, err := s.Next(); !errors.Is(err, ErrClockRollback) {
+			t.Fatalf("expected ErrClockRollback, got %v", err)
+		}
+	})
+
+	t.Run("borrow", func(t *testing.T) {
+		clock := &fakeClock{t: start}
+		layout := Layout{TimeBits: 41, ReservedBits: 1, WorkerBits: 9, SequenceBits: 12}
+		s := newTestSnowflake(t, clock, WithLayout(layout), WithRollback(RollbackBorrow, 0))
+		before := map[int64]struct{}{}
+		for i := 0; i < 3; i++ {
+			id, _ := s.Next()
+			before[id] = struct{}{}
+			clock.add(time.Millisecond)
+		}
+		// 回拨到第一次生成的时间，借用预留位后继续生成，不与回拨前的 ID 重复
+		clock.add(-3 * time.Millisecond)
+		for i := 0; i < 3; i++ {
+			id, err := s.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, dup := before[id]; dup {
+				t.Fatalf("duplicate id %d after rollback", id)
+			}
+			if s.Parse(id).Reserved != 1 {
+				t.Fatalf("reserved bit not set: %+v", s.Parse(id))
+			}
+			clock.add(time.Millisecond)
+		}
+
+		if _, err := NewSnowflake(WithRollback(RollbackBorrow, 0)); !errors.Is(err, ErrInvalidLayout) {
+			t.Fatalf("borrow without reserved bits should fail, got %v", err)
+		}
+	})
+}
+
+// 非法配置
+func TestSnowflakeInvalidOptions(t *testing.T) {
+	if _, err := NewSnowflake(WithLayout(Layout{TimeBits: 50, WorkerBits: 10, SequenceBits: 12})); !errors.Is(err, ErrInvalidLayout) {
+		t.Fatalf("expected ErrInvalidLayout, got %v", err)
+	}
+	if _, err := NewSnowflake(WithWorker(1024)); !errors.Is(err, ErrInvalidWorker) {
+		t.Fatalf("expected ErrInvalidWorker, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package id
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// ULID 128 位、按时间排序的唯一标识：48 位毫秒时间戳 + 80 位随机数。
+// 文本形式为 26 个 Crockford base32 字符，字典序与生成顺序一致。
+type ULID [16]byte
+
+// ULIDGenerator 并发安全的 ULID 生成器
+type ULIDGenerator struct {
+	gen *randomGen
+}
+
+// NewULIDGenerator 创建 ULID 生成器，默认开启单调模式
+func NewULIDGenerator(opts ...GeneratorOption) *ULIDGenerator {
+	return &ULIDGenerator{gen: newRandomGen(16, 64, opts)}
+}
+
+// New 生成一个 ULID，只有读取随机数失败时返回错误
+func (g *ULIDGenerator) New() (ULID, error) {
+	ms, hi, lo, err := g.gen.next()
+	if err != nil {
+		return ULID{}, err
+	}
+	var u ULID
+	putMillis(u[:6], ms)
+	u[6], u[7] = byte(hi>>8), byte(hi)
+	for i := 0; i < 8; i++ {
+		u[8+i] = byte(lo >> (56 - 8*i))
+	}
+	return u, nil
+}
+
+// 包级别的默认生成器
+var defaultULID = NewULIDGenerator()
+
+// NewULID 使用默认生成器生成 ULID，读取随机数失败时 panic
+func NewULID() ULID {
+	u, err := defaultULID.New()
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// ParseULID 解析 ULID，支持 26 位 Crockford base32 与 22 位 base62 两种文本形式
+func ParseULID(s string) (ULID, error) {
+	b, err := parseText(s)
+	return ULID(b), err
+}
+
+// Time 返回 ULID 中的时间戳
+func (u ULID) Time() time.Time { return time.UnixMilli(millis(u[:6])) }
+
+// String 返回 Crockford base32 形式
+func (u ULID) String() string { return encodeBase32(u) }
+
+// Base62 返回 22 位 base62 形式，适合放在 URL 中
+func (u ULID) Base62() string { return encodeBase62(u) }
+
+// IsZero 是否为零值
+func (u ULID) IsZero() bool { return u == ULID{} }
+
+// MarshalText 实现 encoding.TextMarshaler，JSON 中编码为字符串
+func (u ULID) MarshalText() ([]byte, error) { return []byte(u.String()), nil }
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (u *ULID) UnmarshalText(b []byte) error {
+	v, err := ParseULID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// Value 实现 driver.Valuer，以 base32 字符串写入数据库
+func (u ULID) Value() (driver.Value, error) { return u.String(), nil }
+
+// Scan 实现 sql.Scanner，支持字符串与 16 字节的二进制列
+func (u *ULID) Scan(src any) error {
+	b, err := scanID(src, parseText)
+	if err != nil {
+		return err
+	}
+	*u = ULID(b)
+	return nil
+}
+
+// parseText 按长度识别 base32 或 base62 文本
+func parseText(s string) ([16]byte, error) {
+	switch len(s) {
+	case base32Len:
+		return decodeBase32(s)
+	case base62Len:
+		return decodeBase62(s)
+	default:
+		return [16]byte{}, fmt.Errorf("%w: unexpected length %d", ErrInvalidID, len(s))
+	}
+}
+
+// scanID sql.Scanner 的公共逻辑：16 字节按二进制处理，其余按文本解析
+func scanID(src any, parse func(string) ([16]byte, error)) ([16]byte, error) {
+	switch v := src.(type) {
+	case string:
+		return parse(v)
+	case []byte:
+		if len(v) == 16 {
+			return [16]byte(v), nil
+		}
+		return parse(string(v))
+	default:
+		return [16]byte{}, fmt.Errorf("%w: cannot scan %T", ErrInvalidID, src)
+	}
+}
@@ -0,0 +1,182 @@
+package id
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 已知向量
+func TestEncodingVectors(t *testing.T) {
+	b := [16]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xFF
+	}
+	cases := []struct {
+		in       [16]byte
+		b32, b62 string
+	}{
+		{b, "014D2PF2DBSQQG28T5CY4TQKFF", "0296tiiBb3U904RIpygpjj"},
+		{max, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "7n42DGM5Tflk9n8mt7Fhc7"},
+		{[16]byte{}, strings.Repeat("0", 26), strings.Repeat("0", 22)},
+	}
+	for _, c := range cases {
+		if got := encodeBase32(c.in); got != c.b32 {
+			t.Errorf("base32 = %s, want %s", got, c.b32)
+		}
+		if got := encodeBase62(c.in); got != c.b62 {
+			t.Errorf("base62 = %s, want %s", got, c.b62)
+		}
+		if got, err := decodeBase32(strings.ToLower(c.b32)); err != nil || got != c.in {
+			t.Errorf("decode base32 %s = %x, %v", c.b32, got, err)
+		}
+		if got, err := decodeBase62(c.b62); err != nil || got != c.in {
+			t.Errorf("decode base62 %s = %x, %v", c.b62, got, err)
+		}
+	}
+
+	// 溢出与非法字符
+	for _, s := range []string{"8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "7n42DGM5Tflk9n8mt7Fhc8", "0000000000000000000000000U", "short"} {
+		if _, err := parseText(s); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("parseText(%s) should fail, got %v", s, err)
+		}
+	}
+}
+
+// 随机数据编码后字符串顺序与字节顺序一致
+func TestEncodingOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	ids := make([][16]byte, 1000)
+	for i := range ids {
+		r.Read(ids[i][:])
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+	for i := 1; i < len(ids); i++ {
+		if encodeBase32(ids[i-1]) >= encodeBase32(ids[i]) || encodeBase62(ids[i-1]) >= encodeBase62(ids[i]) {
+			t.Fatalf("encoding breaks ordering at %d", i)
+		}
+	}
+}
+
+// 单调模式下同一毫秒内严格递增，溢出时借用下一毫秒
+func TestULIDMonotonic(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	g := NewULIDGenerator()
+	g.gen.now = func() time.Time { return now }
+
+	prev, _ := g.New()
+	for i := 0; i < 10000; i++ {
+		u, err := g.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() <= prev.String() {
+			t.Fatalf("not increasing: %s after %s", u, prev)
+		}
+		prev = u
+	}
+	if !prev.Time().Equal(now) {
+		t.Fatalf("time = %v, want %v", prev.Time(), now)
+	}
+
+	// 随机部分全 1 时再生成，时间戳进一
+	g.gen.hi, g.gen.lo = 0xFFFF, ^uint64(0)
+	u, _ := g.New()
+	if !u.Time().Equal(now.Add(time.Millisecond)) || u.String() <= prev.String() {
+		t.Fatalf("overflow should carry into the next millisecond: %s %v", u, u.Time())
+	}
+}
+
+// 非单调模式下每次都重新取随机数
+func TestULIDNonMonotonic(t *testing.T) {
+	g := NewULIDGenerator(WithMonotonic(false), WithEntropy(bytes.NewReader(make([]byte, 20))))
+	a, _ := g.New()
+	b, _ := g.New()
+	if a != b && a.Time().Equal(b.Time()) {
+		t.Fatal("zero entropy within one millisecond should repeat")
+	}
+	if _, err := g.New(); err == nil {
+		t.Fatal("exhausted entropy should return an error")
+	}
+}
+
+// UUIDv7 的版本与变体，并能被 google/uuid 识别
+func TestUUIDv7(t *testing.T) {
+	g := NewUUIDv7Generator()
+	var prev UUID
+	for i := 0; i < 1000; i++ {
+		u, err := g.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Version() != 7 || u[8]>>6 != 0b10 {
+			t.Fatalf("bad version/variant: %s", u)
+		}
+		if bytes.Compare(u[:], prev[:]) <= 0 {
+			t.Fatalf("not increasing: %s after %s", u, prev)
+		}
+		prev = u
+	}
+
+	std, err := uuid.Parse(prev.String())
+	if err != nil || std.Version() != 7 || std.Variant() != uuid.RFC4122 {
+		t.Fatalf("google/uuid: %v, %v", std, err)
+	}
+	if time.Since(prev.Time()) > time.Minute {
+		t.Fatalf("unexpected time %v", prev.Time())
+	}
+
+	for _, s := range []string{prev.String(), strings.ReplaceAll(prev.String(), "-", ""), prev.Base32(), prev.Base62()} {
+		if got, err := ParseUUID(s); err != nil || got != prev {
+			t.Fatalf("ParseUUID(%s) = %s, %v", s, got, err)
+		}
+	}
+	if _, err := ParseUUID("0190a2f8-1234-7abc-8def+0123456789ab"); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("expected ErrInvalidID, got %v", err)
+	}
+}
+
+// JSON 与 database/sql 接口
+func TestIDMarshaling(t *testing.T) {
+	type row struct {
+		ULID ULID `json:"ulid"`
+		UUID UUID `json:"uuid"`
+	}
+	in := row{ULID: NewULID(), UUID: NewUUIDv7()}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"`+in.ULID.String()+`"`) || !strings.Contains(string(data), `"`+in.UUID.String()+`"`) {
+		t.Fatalf("unexpected json %s", data)
+	}
+	var out row
+	if err := json.Unmarshal(data, &out); err != nil || out != in {
+		t.Fatalf("round trip = %+v, %v", out, err)
+	}
+
+	v, _ := in.ULID.Value()
+	var u ULID
+	if err := u.Scan(v); err != nil || u != in.ULID {
+		t.Fatalf("scan string = %s, %v", u, err)
+	}
+	if err := u.Scan(in.ULID[:]); err != nil || u != in.ULID {
+		t.Fatalf("scan bytes = %s, %v", u, err)
+	}
+	var id UUID
+	v, _ = in.UUID.Value()
+	if err := id.Scan([]byte(v.(string))); err != nil || id != in.UUID {
+		t.Fatalf("scan uuid = %s, %v", id, err)
+	}
+	if err := id.Scan(42); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("expected ErrInvalidID, got %v", err)
+	}
+}
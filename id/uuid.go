@@ -0,0 +1,137 @@
+package id
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// UUID RFC 9562 第 7 版 UUID：48 位毫秒时间戳、4 位版本、12 位 rand_a、2 位变体、62 位 rand_b。
+// 标准文本形式为 8-4-4-4-12 的十六进制，也支持 base32 与 base62 紧凑形式。
+type UUID [16]byte
+
+// UUIDv7Generator 并发安全的 UUIDv7 生成器。
+// 单调模式下把 rand_a 与 rand_b 合起来当作计数器递增（RFC 9562 6.2 节方法 2）。
+type UUIDv7Generator struct {
+	gen *randomGen
+}
+
+// NewUUIDv7Generator 创建 UUIDv7 生成器，默认开启单调模式
+func NewUUIDv7Generator(opts ...GeneratorOption) *UUIDv7Generator {
+	return &UUIDv7Generator{gen: newRandomGen(12, 62, opts)}
+}
+
+// New 生成一个 UUIDv7，只有读取随机数失败时返回错误
+func (g *UUIDv7Generator) New() (UUID, error) {
+	ms, hi, lo, err := g.gen.next()
+	if err != nil {
+		return UUID{}, err
+	}
+	var u UUID
+	putMillis(u[:6], ms)
+	u[6] = 0x70 | byte(hi>>8) // 版本号 7
+	u[7] = byte(hi)
+	lo |= 0b10 << 62 // 变体 10
+	for i := 0; i < 8; i++ {
+		u[8+i] = byte(lo >> (56 - 8*i))
+	}
+	return u, nil
+}
+
+// 包级别的默认生成器
+var defaultUUIDv7 = NewUUIDv7Generator()
+
+// NewUUIDv7 使用默认生成器生成 UUIDv7，读取随机数失败时 panic
+func NewUUIDv7() UUID {
+	u, err := defaultUUIDv7.New()
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// ParseUUID 解析 UUID，支持标准形式（可以不带连字符）、26 位 base32 与 22 位 base62
+func ParseUUID(s string) (UUID, error) {
+	var b [16]byte
+	var err error
+	switch len(s) {
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return UUID{}, fmt.Errorf("%w: bad uuid format %q", ErrInvalidID, s)
+		}
+		err = decodeHex(b[:], s[:8]+s[9:13]+s[14:18]+s[19:23]+s[24:])
+	case 32:
+		err = decodeHex(b[:], s)
+	default:
+		b, err = parseText(s)
+	}
+	return UUID(b), err
+}
+
+// decodeHex 解析 32 个十六进制字符
+func decodeHex(dst []byte, s string) error {
+	if _, err := hex.Decode(dst, []byte(s)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	return nil
+}
+
+// Version 返回版本号
+func (u UUID) Version() int { return int(u[6] >> 4) }
+
+// Time 返回时间戳，只对 UUIDv7 有意义
+func (u UUID) Time() time.Time { return time.UnixMilli(millis(u[:6])) }
+
+// String 返回 8-4-4-4-12 标准形式
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// Base32 返回 26 位 Crockford base32 形式
+func (u UUID) Base32() string { return encodeBase32(u) }
+
+// Base62 返回 22 位 base62 形式，适合放在 URL 中
+func (u UUID) Base62() string { return encodeBase62(u) }
+
+// IsZero 是否为零值
+func (u UUID) IsZero() bool { return u == UUID{} }
+
+// MarshalText 实现 encoding.TextMarshaler，JSON 中编码为标准形式的字符串
+func (u UUID) MarshalText() ([]byte, error) { return []byte(u.String()), nil }
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(b []byte) error {
+	v, err := ParseUUID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// Value 实现 driver.Valuer，以标准形式写入数据库，可以直接对应 PostgreSQL 的 uuid 类型
+func (u UUID) Value() (driver.Value, error) { return u.String(), nil }
+
+// Scan 实现 sql.Scanner，支持字符串与 16 字节的二进制列
+func (u *UUID) Scan(src any) error {
+	b, err := scanID(src, func(s string) ([16]byte, error) {
+		v, err := ParseUUID(s)
+		return [16]byte(v), err
+	})
+	if err != nil {
+		return err
+	}
+	*u = UUID(b)
+	return nil
+}
@@ -0,0 +1,142 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// HeldLock 本地持有的一把锁
+type HeldLock struct {
+	Key     string
+	Owner   string
+	Token   int64         // fencing token
+	Count   int           // 重入次数
+	TTL     time.Duration // 剩余有效期（按本地记录的有效截止时间计算）
+	Renewed bool          // 是否开启了自动续约
+}
+
+// Held 列出当前通过这个 Locker 持有的锁，按 key 排序
+func (t *lockTable) Held() []HeldLock {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	held := make([]HeldLock, 0, len(t.states))
+	for key, st := range t.states {
+		held = append(held, HeldLock{
+			Key:     key,
+			Owner:   st.owner,
+			Token:   st.fencing,
+			Count:   st.count,
+			TTL:     st.validUntil.Sub(now),
+			Renewed: st.renewed,
+		})
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].Key < held[j].Key })
+	return held
+}
+
+// KeyInfo Redis 中一把锁的状态
+type KeyInfo struct {
+	Key     string
+	Held    bool
+	Owner   string        // 持有者的 owner，加锁时没有 owner 为空字符串
+	Value   string        // 锁的 value（持有者的 token）
+	TTL     time.Duration // 剩余过期时间，多节点时取持有该 value 的节点中的最小值
+	Fencing int64         // 当前 fencing 计数器，多节点时取最大值
+	Local   bool          // 是否由这个 Client 持有
+}
+
+// nodeKey 一个节点上的锁状态
+type nodeKey struct {
+	value   string
+	ttl     time.Duration
+	fencing int64
+}
+
+// Inspect 查询任意 key 在 Redis 中的持有者、剩余 TTL 和 fencing 计数器。
+// 多节点模式下只有多数节点上 value 一致时才认为 key 被持有
+func (c *Client) Inspect(ctx context.Context, key string) (KeyInfo, error) {
+	var (
+		mu    sync.Mutex
+		nodes []nodeKey
+	)
+	res := c.each(ctx, func(ctx context.Context, rdb *redis.Client) (bool, error) {
+		pipe := rdb.Pipeline()
+		get := pipe.Get(ctx, key)
+		pttl := pipe.PTTL(ctx, key)
+		fencing := pipe.Get(ctx, fencingKey(key))
+		if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+		n := nodeKey{value: get.Val(), ttl: pttl.Val()}
+		n.fencing, _ = fencing.Int64()
+		mu.Lock()
+		nodes = append(nodes, n)
+		mu.Unlock()
+		return true, nil
+	})
+	if err := c.quorumErr(res); err != nil {
+		return KeyInfo{}, err
+	}
+
+	info := KeyInfo{Key: key}
+	counts := make(map[string]int)
+	for _, n := range nodes {
+		info.Fencing = max(info.Fencing, n.fencing)
+		if n.value != "" {
+			counts[n.value]++
+		}
+	}
+	for v, n := range counts {
+		if n >= c.quorum {
+			info.Held, info.Value, info.Owner = true, v, ownerOf(v)
+		}
+	}
+	if info.Held {
+		info.TTL = -1
+		for _, n := range nodes {
+			if n.value == info.Value && (info.TTL < 0 || n.ttl < info.TTL) {
+				info.TTL = n.ttl
+			}
+		}
+	}
+
+	c.mu.Lock()
+	if st, ok := c.states[key]; ok && st.token == info.Value {
+		info.Local = true
+	}
+	c.mu.Unlock()
+	return info, nil
+}
+
+// lua 脚本：强制删除锁并发布释放通知
+var forceReleaseScript = redis.NewScript(`
+local n = redis.call("DEL", KEYS[1])
+if n == 1 then
+  redis.call("PUBLISH", ARGV[1], "1")
+end
+return n
+`)
+
+// ForceRelease 管理操作：不校验 token 直接删除 key（所有节点），唤醒等待者，返回是否删除了锁。
+// 如果锁由这个 Client 持有，本地同时按丢锁处理。原持有者如果在其他进程，会在下次续约时发现丢锁，
+// 在此之前它仍然认为自己持有锁，因此只应用于处理死锁等异常情况，并配合 fencing token 使用
+func (c *Client) ForceRelease(ctx context.Context, key string) (bool, error) {
+	res := c.each(ctx, func(ctx context.Context, rdb *redis.Client) (bool, error) {
+		n, err := forceReleaseScript.Run(ctx, rdb, []string{key}, releaseChannel(key)).Int()
+		return n == 1, err
+	})
+
+	c.mu.Lock()
+	st, ok := c.states[key]
+	c.mu.Unlock()
+	if ok {
+		c.lose(key, st)
+	}
+	return res.ok > 0, res.err
+}
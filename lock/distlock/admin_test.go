@@ -0,0 +1,120 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Held 列出本地持有的锁，Inspect 查询 Redis 中的状态
+func TestHeldAndInspect(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := NewOwnerContext(context.Background(), "worker-1")
+
+	b, _ := c.Lock(ctx, "b", WithTTL(time.Second))
+	a, _ := c.Lock(ctx, "a", WithTTL(time.Second), WithAutoRenew(true))
+	re, _ := c.Lock(ctx, "a")
+	held := c.Held()
+	if len(held) != 2 || held[0].Key != "a" || held[1].Key != "b" {
+		t.Fatalf("Held = %+v", held)
+	}
+	if h := held[0]; h.Count != 2 || h.Owner != "worker-1" || !h.Renewed || h.TTL <= 0 || h.Token != a.Token() {
+		t.Fatalf("held[0] = %+v", h)
+	}
+
+	info, err := c.Inspect(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Held || !info.Local || info.Owner != "worker-1" || info.Fencing != a.Token() || info.TTL <= 0 {
+		t.Fatalf("Inspect = %+v", info)
+	}
+	// 其他 Client 看到的不是本地持有
+	if info, _ := NewClient(rdb).Inspect(ctx, "a"); !info.Held || info.Local {
+		t.Fatalf("Inspect from other client = %+v", info)
+	}
+
+	re.Unlock(ctx)
+	a.Unlock(ctx)
+	b.Unlock(ctx)
+	if held := c.Held(); len(held) != 0 {
+		t.Fatalf("Held after unlock = %+v", held)
+	}
+	if info, _ := c.Inspect(ctx, "a"); info.Held || info.Fencing != 1 {
+		t.Fatalf("Inspect after unlock = %+v", info)
+	}
+}
+
+// ForceRelease 删除锁并唤醒等待者，本地持有者按丢锁处理
+func TestForceRelease(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	l, err := c.TryLock(ctx, "res", time.Minute)
+	if err != nil || l == nil {
+		t.Fatalf("TryLock = %v, %v", l, err)
+	}
+	got := make(chan error, 1)
+	go func() {
+		w, err := NewClient(rdb).Lock(ctx, "res", WithFallbackInterval(10*time.Second), WithTryTimeout(5*time.Second))
+		if err == nil {
+			w.Unlock(ctx)
+		}
+		got <- err
+	}()
+	time.Sleep(30 * time.Millisecond)
+
+	if ok, err := c.ForceRelease(ctx, "res"); err != nil || !ok {
+		t.Fatalf("ForceRelease = %v, %v", ok, err)
+	}
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter should be woken by ForceRelease")
+	}
+	<-l.Done()
+	if err := l.Unlock(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+	if ok, _ := c.ForceRelease(ctx, "res"); ok {
+		t.Fatal("releasing a free key should report false")
+	}
+}
+
+// Counters 统计加锁结果、竞争、续约失败和丢锁
+func TestCounters(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	var m Counters
+	c := NewClient(rdb, WithMetrics(&m))
+	ctx := context.Background()
+
+	l, _ := c.Lock(ctx, "res", WithTTL(time.Second), WithAutoRenew(true), WithRenewInterval(10*time.Millisecond))
+	if l2, _ := c.TryLockWith(ctx, "res"); l2 != nil {
+		t.Fatal("TryLock should fail")
+	}
+	s.Del("res")
+	<-l.Done()
+
+	st := m.Stats()
+	if st.Acquires != 1 || st.AcquireFailures != 1 || st.Contentions != 1 || st.RenewFailures != 1 || st.Losses != 1 {
+		t.Fatalf("Stats = %+v", st)
+	}
+	if st.AverageAcquireTime() <= 0 {
+		t.Fatal("AverageAcquireTime should be positive")
+	}
+}
@@ -3,9 +3,8 @@ package dlock
 import (
 	"context"
 	"errors"
-	"github.com/google/uuid"
 	"math/rand"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -20,27 +19,38 @@ func init() {
 var (
 	ErrAcquireTimeout = errors.New("dlock: acquire lock timeout")
 	ErrNotOwner       = errors.New("dlock: lock not owned by this client")
+	ErrLockLost       = errors.New("dlock: lock lost before unlock")
 )
 
 // Client 封装 Redis 客户端以及本地说状态
 type Client struct {
-	rdb *redis.Client
+	nodes  []*redis.Client // 单节点模式只有一个；Redlock 模式为 N 个相互独立的实例
+	quorum int             // 加锁、续约需要成功的节点数
+	cfg    ClientOptions
 
-	mu     sync.Mutex
-	states map[string]*lockState
+	lockTable
 }
 
 // 每个 key 对应的本地锁状态实现（实现可重入 + 续约协程管理）
 type lockState struct {
-	token  string
-	count  int
-	cancel context.CancelFunc // 用于停止 watchDog
+	owner      string // 持有者标识，为空表示不可重入
+	token      string
+	fencing    int64 // 本次持有对应的 fencing token
+	count      int
+	ctx        context.Context         // 持有期间有效，释放或丢锁时取消；同时用于停止 watchdog
+	cancel     context.CancelCauseFunc // 丢锁时 cause 为 ErrLockLost
+	lost       bool                    // 是否已经丢锁，受 lockTable.mu 保护
+	validUntil time.Time               // 扣除获取耗时与时钟漂移后的有效截止时间，续约成功后更新
+	ttl        time.Duration           // 续约使用的 TTL
+	renewed    bool                    // 是否开启了自动续约
 }
 
-// Lock 是用户拿到的锁句柄
+// Lock 是用户拿到的锁句柄，绑定到获取时的那一次持有，所有 Locker 实现返回的都是它
 type Lock struct {
-	key    string
-	client *Client
+	key      string
+	t        *lockTable
+	state    *lockState
+	released bool // 该句柄是否已经 Unlock，受 lockTable.mu 保护
 }
 
 // LockOptions 控制加锁行为
@@ -48,15 +58,22 @@ type LockOptions struct {
 	// Duration 两个瞬间之间的经过时间
 	TTL           time.Duration // 锁过期时间（服务端TTL）
 	TryTimeout    time.Duration // 总共等待多长时间去获取锁（0 表示一直等到 ctx 取消）
-	RetryInterval time.Duration // 每次重试基础间隔
+	RetryInterval time.Duration // 关闭 PubSub 时每次重试基础间隔
 	AutoRenew     bool          // 是否自动续约
 	RenewInterval time.Duration // 续约间隔（<= TTL, 默认 TTL/3）
+	Owner         string        // 持有者标识，同一持有者可以重入（默认取 ctx 中的 owner，都没有时不可重入）
+
+	PubSub           bool          // 订阅释放通知，被唤醒后再重试（默认开启）
+	FallbackInterval time.Duration // 开启 PubSub 时的兜底轮询间隔，用于发现过期这类不会发通知的释放（默认 1s）
+	Fair             bool          // 公平模式：等待者按到达顺序获取锁（只在使用 Fair 的调用之间公平）
+	Fencing          bool          // 生成 fencing token（默认开启）。关闭后不再维护永久保存的计数器，Token 返回 0，适合 key 数量没有上限的场景
 }
 
 // 一些默认值
 const (
 	defaultTTL           = 10 * time.Second
 	defaultRetryInterval = 50 * time.Millisecond
+	defaultFallback      = time.Second
 	jitterFactor         = 0.5 // 重试间隔抖动比例
 )
 
@@ -68,12 +85,59 @@ func DefaultLockOptions() LockOptions {
 		RetryInterval: defaultRetryInterval,
 		AutoRenew:     false,
 		RenewInterval: 0,
+
+		PubSub:           true,
+		FallbackInterval: defaultFallback,
+		Fencing:          true,
 	}
 }
 
 // Option 函数式编程
 type Option func(*LockOptions)
 
+// lockOptions 合并配置并补齐默认值
+func lockOptions(opts []Option) LockOptions {
+	// 默认配置
+	cfg := DefaultLockOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	// base case
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+	if cfg.FallbackInterval <= 0 {
+		cfg.FallbackInterval = defaultFallback
+	}
+	return cfg
+}
+
+// deadline 根据 TryTimeout 计算整体等待截止时间，零值表示不限制
+func (o LockOptions) deadline() time.Time {
+	if o.TryTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(o.TryTimeout)
+}
+
+// renewInterval 自动续约间隔，没有开启 AutoRenew 时返回 0
+func (o LockOptions) renewInterval() time.Duration {
+	if !o.AutoRenew {
+		return 0
+	}
+	interval := o.RenewInterval
+	if interval <= 0 || interval >= o.TTL {
+		interval = o.TTL / 3
+		if interval <= 0 {
+			interval = time.Millisecond * 100
+		}
+	}
+	return interval
+}
+
 // WithTTL 初始化 TTL
 func WithTTL(ttl time.Duration) Option {
 	return func(o *LockOptions) {
@@ -102,6 +166,56 @@ func WithAutoRenew(enable bool) Option {
 	}
 }
 
+// WithOwner 初始化 Owner
+func WithOwner(owner string) Option {
+	return func(o *LockOptions) {
+		o.Owner = owner
+	}
+}
+
+// WithPubSub 初始化 PubSub
+func WithPubSub(enable bool) Option {
+	return func(o *LockOptions) {
+		o.PubSub = enable
+	}
+}
+
+// WithFallbackInterval 初始化 FallbackInterval
+func WithFallbackInterval(d time.Duration) Option {
+	return func(o *LockOptions) {
+		o.FallbackInterval = d
+	}
+}
+
+// WithFair 初始化 Fair
+func WithFair(enable bool) Option {
+	return func(o *LockOptions) {
+		o.Fair = enable
+	}
+}
+
+// WithFencing 初始化 Fencing
+func WithFencing(enable bool) Option {
+	return func(o *LockOptions) {
+		o.Fencing = enable
+	}
+}
+
+// ownerKey context 中保存 owner 的 key
+type ownerKey struct{}
+
+// NewOwnerContext 返回携带持有者标识的 ctx。
+// 同一个 Client 上，只有 owner 相同的调用才会重入；owner 不同或为空时与其他进程一样需要等待锁释放
+func NewOwnerContext(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext 读取 ctx 中的持有者标识
+func OwnerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
 // WithRenewInterval 初始化 RenewInterval
 func WithRenewInterval(d time.Duration) Option {
 	return func(o *LockOptions) {
@@ -109,18 +223,64 @@ func WithRenewInterval(d time.Duration) Option {
 	}
 }
 
-// NewClient 用外部创建好的 go-redis Client 初始化
-func NewClient(rdb *redis.Client) *Client {
-	return &Client{
-		rdb:    rdb,
-		states: make(map[string]*lockState),
+// NewClient 用外部创建好的 go-redis Client 初始化（单节点模式）
+func NewClient(rdb *redis.Client, opts ...ClientOption) *Client {
+	return newClient([]*redis.Client{rdb}, opts)
+}
+
+// lua 脚本：加锁，成功时递增 fencing 计数器并返回新值，失败返回 0。
+// 计数器不设过期时间，保证同一个 key 的 token 单调递增
+var acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+  return redis.call("INCR", KEYS[2])
+else
+  return 0
+end
+`)
+
+// lua 脚本：只加锁，不生成 fencing token
+var acquireNoFencingScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+  return 1
+end
+return 0
+`)
+
+// lua 脚本：把 fencing 计数器抬高到不小于 ARGV[1]（Redlock 模式使用）
+var raiseScript = redis.NewScript(`
+if tonumber(redis.call("GET", KEYS[1]) or "0") < tonumber(ARGV[1]) then
+  redis.call("SET", KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// fencingKey 锁对应的 fencing 计数器，与锁在同一个 slot，加锁脚本同时操作两者
+func fencingKey(key string) string {
+	return siblingKey(key, ":fencing")
+}
+
+// siblingKey 与 key 落在同一个 Redis Cluster slot 的辅助 key，可以和 key 在同一个脚本中操作。
+// key 没有 hash tag 时整个 key 参与 slot 计算，辅助 key 用 {key} 作为 hash tag 即可与之相同；
+// key 自带 hash tag 时直接追加后缀。key 含有 '}' 却没有合法 hash tag 时无法保证，这样的 key 应自行加上 hash tag
+func siblingKey(key, suffix string) string {
+	if hasHashTag(key) || strings.Contains(key, "}") {
+		return key + suffix
 	}
+	return "{" + key + "}" + suffix
+}
+
+// hasHashTag key 是否带有 Redis Cluster 的 hash tag：第一个 '{' 之后存在 '}' 且中间不为空
+func hasHashTag(key string) bool {
+	i := strings.IndexByte(key, '{')
+	return i >= 0 && strings.IndexByte(key[i+1:], '}') > 0
 }
 
-// lua 脚本：解锁（只有 value 匹配时才删除）
+// lua 脚本：解锁（只有 value 匹配时才删除），删除后在 ARGV[2] 上发布释放通知
 var unlockScript = redis.NewScript(`
 if redis.call("GET", KEYS[1]) == ARGV[1] then
-  return redis.call("DEL", KEYS[1])
+  redis.call("DEL", KEYS[1])
+  redis.call("PUBLISH", ARGV[2], "1")
+  return 1
 else
   return 0
 end
@@ -135,85 +295,83 @@ else
 end
 `)
 
-// TryLock 尝试一次获取锁（非阻塞），成功返回 (*Lock, nil)，失败但没有错误返回 (nil, nil)
+// TryLock 尝试一次获取锁（非阻塞），成功返回 (*Lock, nil)，失败但没有错误返回 (nil, nil)。
+// ttl <= 0 时使用默认 TTL，需要其他配置时使用 TryLockWith
 func (c *Client) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
-	// base case
-	if ttl < 0 {
-		ttl = defaultTTL
-	}
+	return c.TryLockWith(ctx, key, WithTTL(ttl))
+}
+
+// TryLockWith 与 TryLock 相同，但与 Lock 使用相同的 Option，等待相关的配置（TryTimeout、PubSub、Fair 等）不生效
+func (c *Client) TryLockWith(ctx context.Context, key string, opts ...Option) (*Lock, error) {
+	start := time.Now()
+	l, err := c.tryLock(ctx, key, lockOptions(opts))
+	c.metrics.Acquire(key, time.Since(start), l != nil)
+	return l, err
+}
 
-	// 先检测本地是否已经持有：实现“可重入锁”
-	c.mu.Lock()
-	if st, ok := c.states[key]; ok {
-		st.count++
-		c.mu.Unlock()
-		return &Lock{key: key, client: c}, nil
+// tryLock 尝试一次获取锁
+func (c *Client) tryLock(ctx context.Context, key string, cfg LockOptions) (*Lock, error) {
+
+	// 先检测同一个 owner 是否已经持有：实现“可重入锁”
+	if cfg.Owner == "" {
+		cfg.Owner = OwnerFromContext(ctx)
+	}
+	if l := c.reenter(key, cfg.Owner); l != nil {
+		return l, nil
 	}
-	c.mu.Unlock()
 
-	token := uuid.NewString()
-	// setNx 操作执行分布式锁
-	ok, err := c.rdb.SetNX(ctx, key, token, ttl).Result()
+	token := newToken(cfg.Owner)
+	// setNx 操作执行分布式锁（Redlock 模式下需要多数节点成功）
+	ok, fencing, validUntil, err := c.acquire(ctx, key, token, cfg.TTL, cfg.Fencing)
 	if err != nil {
 		return nil, err
 	}
 
 	// 说明有人持有锁
 	if !ok {
+		c.metrics.Contention(key)
 		return nil, nil
 	}
 
 	// 写入本地状态
-	c.mu.Lock()
-	c.states[key] = &lockState{
-		token: token,
-		count: 1,
-	}
-	c.mu.Unlock()
-
-	return &Lock{key: key, client: c}, nil
+	return c.hold(key, cfg.Owner, token, fencing, validUntil, cfg.TTL, cfg.renewInterval()), nil
 }
 
 // Lock 带重试 & 超时获取锁
 //
 // 语义：
-//   - 如果当前进程已经持有该 key 的锁，则只是 count++（可重入），立即返回
+//   - 如果同一个 owner（WithOwner 或 NewOwnerContext）已经持有该 key 的锁，则只是 count++（可重入），立即返回
 //   - 否则循环尝试 SET NX，直到：
 //   - 成功拿到锁；或者
 //   - TryTimeout 到达；或者
 //   - ctx 被取消
+//   - 两次尝试之间订阅 key 的释放通知等待唤醒，兜底按 FallbackInterval 轮询；关闭 PubSub 时按 RetryInterval 轮询
+//   - Fair 模式下先在队列中排队，轮到队头才尝试加锁
 func (c *Client) Lock(ctx context.Context, key string, opts ...Option) (*Lock, error) {
-	// 默认配置
-	cfg := DefaultLockOptions()
-	for _, fn := range opts {
-		fn(&cfg)
-	}
-	// base case
-	if cfg.TTL <= 0 {
-		cfg.TTL = defaultTTL
-	}
-	if cfg.RetryInterval <= 0 {
-		cfg.RetryInterval = defaultRetryInterval
-	}
+	start := time.Now()
+	l, err := c.lock(ctx, key, lockOptions(opts))
+	c.metrics.Acquire(key, time.Since(start), l != nil)
+	return l, err
+}
+
+// lock 带重试 & 超时获取锁
+func (c *Client) lock(ctx context.Context, key string, cfg LockOptions) (*Lock, error) {
 
-	// 先看看本地是否已经持有（可重入）
-	c.mu.Lock()
-	if st, ok := c.states[key]; ok {
-		st.count++
-		c.mu.Unlock()
-		return &Lock{key: key, client: c}, nil
+	// 先看看同一个 owner 是否已经持有（可重入）
+	if cfg.Owner == "" {
+		cfg.Owner = OwnerFromContext(ctx)
+	}
+	if l := c.reenter(key, cfg.Owner); l != nil {
+		return l, nil
 	}
-	c.mu.Unlock()
 
 	// 计算整体超时 deadline（如果配置了 TryTimeout）
-	var deadline time.Time
-	if cfg.TryTimeout > 0 {
-		// 添加等待时间
-		deadline = time.Now().Add(cfg.TryTimeout)
-	}
+	deadline := cfg.deadline()
 
 	// 使用 uuid 的方式，创建 token
-	token := uuid.NewString()
+	token := newToken(cfg.Owner)
+	w := c.newWaiter(key, token, cfg)
+	defer w.close()
 
 	for {
 		// 先检查 ctx
@@ -228,120 +386,278 @@ func (c *Client) Lock(ctx context.Context, key string, opts ...Option) (*Lock, e
 			return nil, ErrAcquireTimeout
 		}
 
-		ok, err := c.rdb.SetNX(ctx, key, token, cfg.TTL).Result()
+		// 等待期间同一个 owner 的其他调用可能已经拿到了锁
+		if l := c.reenter(key, cfg.Owner); l != nil {
+			return l, nil
+		}
+
+		turn, err := w.turn(ctx)
 		if err != nil {
 			return nil, err
 		}
+		ok := false
+		var (
+			fencing    int64
+			validUntil time.Time
+		)
+		if turn {
+			ok, fencing, validUntil, err = c.acquire(ctx, key, token, cfg.TTL, cfg.Fencing)
+			if err != nil {
+				return nil, err
+			}
+		}
 		if ok {
+			w.acquired = true
 			// 成功拿到锁，记录本地状态 + 启动 watchdog（如果需要）
-			var cancel context.CancelFunc
-			if cfg.AutoRenew {
-				// 启动续约协程
-				renewInterval := cfg.RenewInterval
-				if renewInterval <= 0 || renewInterval >= cfg.TTL {
-					renewInterval = cfg.TTL / 3
-					if renewInterval <= 0 {
-						renewInterval = time.Millisecond * 100
-					}
-				}
-				var wctx context.Context
-				wctx, cancel = context.WithCancel(context.Background())
-				go c.watchdog(wctx, key, token, cfg.TTL, renewInterval)
-			}
-
-			c.mu.Lock()
-			c.states[key] = &lockState{
-				token:  token,
-				count:  1,
-				cancel: cancel,
-			}
-			c.mu.Unlock()
+			return c.hold(key, cfg.Owner, token, fencing, validUntil, cfg.TTL, cfg.renewInterval()), nil
+		}
 
-			return &Lock{key: key, client: c}, nil
+		// 没拿到锁：等待释放通知或者睡一会儿再重试
+		c.metrics.Contention(key)
+		if err := w.wait(ctx, deadline); err != nil {
+			return nil, err
 		}
+	}
+}
 
-		// 没拿到锁：睡一会儿再重试（带一点随机抖动）
-		sleep := cfg.RetryInterval
-		jitter := time.Duration(rand.Float64() * jitterFactor * float64(sleep))
-		sleep = sleep + jitter
+// hold 记录本地状态并启动 watchdog，返回绑定到这次持有的句柄
+func (t *lockTable) hold(key, owner, token string, fencing int64, validUntil time.Time, ttl, renewInterval time.Duration) *Lock {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	st := &lockState{
+		owner:      owner,
+		token:      token,
+		fencing:    fencing,
+		count:      1,
+		ctx:        ctx,
+		cancel:     cancel,
+		validUntil: validUntil,
+		ttl:        ttl,
+		renewed:    renewInterval > 0,
+	}
+	t.mu.Lock()
+	t.states[key] = st
+	t.mu.Unlock()
 
-		// 防止因 ctx 取消而多睡
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-time.After(sleep):
+	go t.watchdog(key, st, renewInterval)
+	return &Lock{key: key, t: t, state: st}
+}
+
+// watchdog 监视一次持有，直到释放或丢锁
+func (t *lockTable) watchdog(key string, st *lockState, interval time.Duration) {
+	renew := func(ctx context.Context) (bool, time.Time, error) {
+		// 只有 token 匹配时才会续约，Redlock 模式下需要多数节点成功
+		ok, validUntil, err := t.backend.renew(ctx, key, st.token, st.ttl)
+		if ok {
+			t.mu.Lock()
+			st.validUntil = validUntil
+			t.mu.Unlock()
+		} else {
+			t.metrics.RenewFailure(key, err)
 		}
+		return ok, validUntil, err
 	}
+	validUntil := func() time.Time {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return st.validUntil
+	}
+	watch(st.ctx, validUntil, interval, renew, func() { t.lose(key, st) })
 }
 
-// watchdog 定期续约锁，直到 ctx 被取消或续约失败
-func (c *Client) watchdog(ctx context.Context, key, token string, ttl, interval time.Duration) {
-	// 为了安全一点，我们续约的 TTL 仍然用原始 ttl
-	ttlMs := ttl.Milliseconds()
-	if ttlMs <= 0 {
-		ttlMs = defaultTTL.Milliseconds()
+// watch 监视一次持有，直到 ctx 取消（释放）或者调用 lose（丢失）：
+// interval > 0 时定期调用 renew 续约，续约被拒绝（key 已过期或被他人持有）、或出错一直持续到有效期结束都视为丢失；
+// 否则在有效期结束时视为丢失。validUntil 返回当前的有效截止时间，手动续约后会延长
+func watch(ctx context.Context, validUntil func() time.Time, interval time.Duration,
+	renew func(ctx context.Context) (bool, time.Time, error), lose func()) {
+	expire := time.NewTimer(time.Until(validUntil()))
+	defer expire.Stop()
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
 	}
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			// 调用 Lua 续约脚本；只有 token 匹配时才会 PEXPIRE
-			_, err := renewScript.Run(ctx, c.rdb, []string{key}, token, ttlMs).Int()
+		case <-expire.C:
+			if d := time.Until(validUntil()); d > 0 {
+				// 期间手动续约过
+				expire.Reset(d)
+				continue
+			}
+			lose()
+			return
+		case <-tick:
+			ok, validUntil, err := renew(ctx)
 			if err != nil {
-				// 一般可以记录日志，这里我们只选择“停止 watchdog”
+				// 网络错误不代表锁丢了，有效期内继续重试，到期由 expire 处理
+				continue
+			}
+			if !ok {
+				lose()
 				return
 			}
+			expire.Reset(time.Until(validUntil))
 		}
 	}
 }
 
+// lose 标记丢锁：删除本地状态（之后同一个 owner 需要重新加锁）并以 ErrLockLost 取消持有期间的 ctx
+func (t *lockTable) lose(key string, st *lockState) {
+	t.mu.Lock()
+	if t.states[key] != st {
+		// 已经释放
+		t.mu.Unlock()
+		return
+	}
+	st.lost = true
+	delete(t.states, key)
+	t.mu.Unlock()
+	st.cancel(ErrLockLost)
+	t.metrics.Lost(key)
+}
+
 // Unlock 释放锁。
 // 可重入场景下，需要调用 Unlock 与 Lock 调用次数匹配；
-// 只有最后一次 Unlock 才会真正删除 Redis 里的 key。
+// 只有最后一次 Unlock 才会真正删除后端（Redis、数据库等）中的锁。同一个句柄重复 Unlock 返回 ErrNotOwner；
+// 锁在释放之前已经丢失（续约失败、过期或被删除）时返回 ErrLockLost，说明临界区可能已经不受保护。
 func (l *Lock) Unlock(ctx context.Context) error {
-	return l.client.unlock(ctx, l.key)
+	return l.t.unlock(ctx, l)
 }
 
 // 内部解锁逻辑
-func (c *Client) unlock(ctx context.Context, key string) error {
-	c.mu.Lock()
-	st, ok := c.states[key]
-	if !ok {
-		c.mu.Unlock()
+func (t *lockTable) unlock(ctx context.Context, l *Lock) error {
+	key := l.key
+	t.mu.Lock()
+	st := l.state
+	if l.released {
+		t.mu.Unlock()
+		return ErrNotOwner
+	}
+	if st.lost {
+		l.released = true
+		t.mu.Unlock()
+		return ErrLockLost
+	}
+	if t.states[key] != st {
+		t.mu.Unlock()
 		return ErrNotOwner
 	}
+	l.released = true
 
 	st.count--
 	if st.count > 0 {
 		// 还有重入层数，不真正释放
-		c.mu.Unlock()
+		t.mu.Unlock()
 		return nil
 	}
 
 	// 最后一次解锁：停止 watchdog + 删除本地状态
-	if st.cancel != nil {
-		st.cancel()
-	}
+	st.cancel(nil)
 	token := st.token
-	delete(c.states, key)
-	c.mu.Unlock()
+	delete(t.states, key)
+	t.mu.Unlock()
 
-	// 用 Lua 脚本安全删除 Redis 锁（防止误删他人锁）
-	res, err := unlockScript.Run(ctx, c.rdb, []string{key}, token).Int()
+	// 只删除 token 匹配的锁（防止误删他人锁）
+	ok, err := t.backend.free(ctx, key, token)
 	if err != nil {
 		return err
 	}
-	if res == 0 {
-		// 没删掉：要么锁已过期，要么 token 不匹配
+	if !ok {
+		// 没删掉：锁已过期或者被删除，还没来得及被 watchdog 发现
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Renew 立即续约一次（使用加锁时的 TTL），不需要等待自动续约。锁已经丢失时返回 ErrLockLost
+func (l *Lock) Renew(ctx context.Context) error {
+	l.t.mu.Lock()
+	if !l.held() {
+		lost := l.state.lost
+		l.t.mu.Unlock()
+		if lost {
+			return ErrLockLost
+		}
 		return ErrNotOwner
 	}
+	st := l.state
+	l.t.mu.Unlock()
+
+	ok, validUntil, err := l.t.backend.renew(ctx, l.key, st.token, st.ttl)
+	if !ok {
+		l.t.metrics.RenewFailure(l.key, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		l.t.lose(l.key, st)
+		return ErrLockLost
+	}
+	l.t.mu.Lock()
+	if st.validUntil.Before(validUntil) {
+		st.validUntil = validUntil
+	}
+	l.t.mu.Unlock()
 	return nil
 }
 
 // Key 一些辅助方法，方便调试
 func (l *Lock) Key() string { return l.key }
+
+// Done 返回的 channel 在锁释放或丢失时关闭，临界区内可以用它及时中止
+func (l *Lock) Done() <-chan struct{} { return l.state.ctx.Done() }
+
+// Context 返回持有期间有效的 ctx，锁释放或丢失时取消；丢锁时 context.Cause 返回 ErrLockLost。
+// 同一次持有的所有重入句柄共享这个 ctx
+func (l *Lock) Context() context.Context { return l.state.ctx }
+
+// Owner 返回持有者标识，不可重入的锁返回空字符串
+func (l *Lock) Owner() string { return l.state.owner }
+
+// held 句柄对应的持有是否仍然有效，调用方需持有 lockTable.mu
+func (l *Lock) held() bool {
+	return !l.released && l.t.states[l.key] == l.state
+}
+
+// Token 返回本次持有的 fencing token，同一个 key 每次加锁得到的 token 严格递增；锁已释放时返回 0。
+// 访问受保护的资源时带上它，资源端用 Fence 拒绝比已见过的 token 更小的请求，
+// 这样即使持有者因 GC 停顿等原因在锁过期后才恢复，也无法覆盖新持有者的写入。
+func (l *Lock) Token() int64 {
+	l.t.mu.Lock()
+	defer l.t.mu.Unlock()
+	if l.held() {
+		return l.state.fencing
+	}
+	return 0
+}
+
+// ValidUntil 返回锁的有效截止时间（已扣除获取耗时与时钟漂移），锁已释放时返回零值。
+// 业务应在该时间之前完成临界区操作。
+func (l *Lock) ValidUntil() time.Time {
+	l.t.mu.Lock()
+	defer l.t.mu.Unlock()
+	if l.held() {
+		return l.state.validUntil
+	}
+	return time.Time{}
+}
+
+// reenter owner 已经持有 key 时增加重入计数并返回新的句柄，否则返回 nil
+func (t *lockTable) reenter(key, owner string) *Lock {
+	if owner == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.states[key]
+	if !ok || st.owner != owner {
+		return nil
+	}
+	st.count++
+	return &Lock{key: key, t: t, state: st}
+}
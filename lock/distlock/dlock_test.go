@@ -0,0 +1,149 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 只有 owner 相同的调用才会重入，不同 owner 或没有 owner 都需要等待
+func TestOwnerReentrancy(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	a := NewOwnerContext(context.Background(), "a")
+	b := NewOwnerContext(context.Background(), "b")
+
+	l, err := c.TryLock(a, "res", time.Second)
+	if err != nil || l == nil {
+		t.Fatalf("TryLock = %v, %v", l, err)
+	}
+	if l.Owner() != "a" {
+		t.Fatalf("Owner = %q", l.Owner())
+	}
+
+	// 不同 owner 拿不到锁
+	if other, err := c.TryLock(b, "res", time.Second); err != nil || other != nil {
+		t.Fatalf("other owner TryLock = %v, %v", other, err)
+	}
+	// 没有 owner 时不可重入
+	_, err = c.Lock(context.Background(), "res", WithTryTimeout(50*time.Millisecond), WithRetryInterval(10*time.Millisecond))
+	if !errors.Is(err, ErrAcquireTimeout) {
+		t.Fatalf("expected ErrAcquireTimeout, got %v", err)
+	}
+	// WithOwner 与 ctx 中的 owner 等价
+	re, err := c.Lock(context.Background(), "res", WithOwner("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.Token() != l.Token() {
+		t.Fatalf("reentrant token = %d, want %d", re.Token(), l.Token())
+	}
+
+	// 同一个句柄重复 Unlock 不会多减计数
+	if err := re.Unlock(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := re.Unlock(a); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected ErrNotOwner, got %v", err)
+	}
+	if !s.Exists("res") {
+		t.Fatal("outer lock should still be held")
+	}
+	if err := l.Unlock(a); err != nil {
+		t.Fatal(err)
+	}
+
+	// 释放后其他 owner 可以获取
+	other, err := c.TryLock(b, "res", time.Second)
+	if err != nil || other == nil {
+		t.Fatalf("TryLock after release = %v, %v", other, err)
+	}
+	// 旧句柄不能释放新持有者的锁
+	if err := l.Unlock(a); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected ErrNotOwner, got %v", err)
+	}
+	other.Unlock(b)
+}
+
+// 锁过期、被删除时 Done 关闭，Unlock 返回 ErrLockLost
+func TestLockLost(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	// 没有自动续约：有效期结束即视为丢锁
+	l, err := c.Lock(ctx, "expire", WithTTL(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done should be closed after the lock expires")
+	}
+	if cause := context.Cause(l.Context()); !errors.Is(cause, ErrLockLost) {
+		t.Fatalf("cause = %v, want ErrLockLost", cause)
+	}
+	if l.Token() != 0 {
+		t.Fatal("lost lock should report token 0")
+	}
+	if err := l.Unlock(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+
+	// 自动续约：key 被删除后续约失败
+	l, err = c.Lock(ctx, "deleted", WithTTL(time.Second), WithAutoRenew(true), WithRenewInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Del("deleted")
+	select {
+	case <-l.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done should be closed after renewal fails")
+	}
+	if err := l.Unlock(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+	// 丢锁后可以重新获取
+	if l, err := c.TryLock(ctx, "deleted", time.Second); err != nil || l == nil {
+		t.Fatalf("TryLock after lost = %v, %v", l, err)
+	}
+}
+
+// 正常释放时 Context 以 context.Canceled 结束
+func TestLockContextReleased(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := NewOwnerContext(context.Background(), "a")
+
+	l, err := c.Lock(ctx, "res")
+	if err != nil {
+		t.Fatal(err)
+	}
+	re, _ := c.Lock(ctx, "res")
+	if re.Context() != l.Context() {
+		t.Fatal("reentrant handles should share the context")
+	}
+	re.Unlock(ctx)
+	if l.Context().Err() != nil {
+		t.Fatal("context should stay alive while the lock is held")
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-l.Done()
+	if cause := context.Cause(l.Context()); cause != context.Canceled {
+		t.Fatalf("cause = %v, want context.Canceled", cause)
+	}
+}
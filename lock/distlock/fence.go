@@ -0,0 +1,65 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrStaleToken 写入携带的 fencing token 比资源已经见过的更小，说明锁已经被其他人重新获取
+var ErrStaleToken = errors.New("dlock: stale fencing token")
+
+// Fence 资源端的 fencing token 校验（进程内），记录每个资源见过的最大 token。
+// 同一个 token 可以多次写入；更小的 token 会被拒绝。
+type Fence struct {
+	mu   sync.Mutex
+	last map[string]int64
+}
+
+// NewFence 创建进程内的 Fence
+func NewFence() *Fence {
+	return &Fence{last: make(map[string]int64)}
+}
+
+// Check 校验 token 并记录，token 过期时返回 ErrStaleToken。调用方应在同一临界区内完成写入
+func (f *Fence) Check(resource string, token int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if last := f.last[resource]; token < last {
+		return fmt.Errorf("%w: %d < %d", ErrStaleToken, token, last)
+	}
+	f.last[resource] = token
+	return nil
+}
+
+// lua 脚本：token 不小于记录值时更新记录并执行写入（SET KEYS[2] ARGV[2]），否则返回记录值
+var fencedSetScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tonumber(ARGV[1]) < last then
+  return last
+end
+redis.call("SET", KEYS[1], ARGV[1])
+redis.call("SET", KEYS[2], ARGV[2])
+return -1
+`)
+
+// FencedSet 供使用 Redis 作为存储的一方使用：只有 token 不小于 key 已见过的最大 token 时才写入 value，
+// 校验与写入在一个 Lua 脚本中原子完成，token 过期时返回 ErrStaleToken
+func FencedSet(ctx context.Context, rdb *redis.Client, key string, value any, token int64) error {
+	last, err := fencedSetScript.Run(ctx, rdb, []string{fenceKey(key), key}, token, value).Int64()
+	if err != nil {
+		return err
+	}
+	if last >= 0 {
+		return fmt.Errorf("%w: %d < %d", ErrStaleToken, token, last)
+	}
+	return nil
+}
+
+// fenceKey 记录 key 已见过的最大 token，与 key 落在同一个 Redis Cluster slot
+func fenceKey(key string) string {
+	return siblingKey(key, ":fence")
+}
@@ -0,0 +1,179 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 单节点下每次加锁的 token 递增，重入时不变
+func TestFencingToken(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := NewOwnerContext(context.Background(), "worker-1")
+
+	var last int64
+	for i := 0; i < 3; i++ {
+		l, err := c.Lock(ctx, "res")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Token() <= last {
+			t.Fatalf("token %d not greater than %d", l.Token(), last)
+		}
+		last = l.Token()
+		re, _ := c.Lock(ctx, "res")
+		if re.Token() != last {
+			t.Fatalf("reentrant token = %d, want %d", re.Token(), last)
+		}
+		re.Unlock(ctx)
+		l.Unlock(ctx)
+		if l.Token() != 0 {
+			t.Fatal("released lock should report token 0")
+		}
+	}
+}
+
+// Redlock 模式下即使各节点计数器不同，token 依然单调递增
+func TestRedlockFencingMonotonic(t *testing.T) {
+	servers, clients := newNodes(t, 3)
+	// 第一个节点的计数器远大于其他节点
+	servers[0].Set(fencingKey("res"), "100")
+	c := NewRedlockClient(clients)
+	ctx := context.Background()
+
+	l, err := c.TryLock(ctx, "res", time.Second)
+	if err != nil || l == nil {
+		t.Fatal(err)
+	}
+	first := l.Token()
+	if first != 101 {
+		t.Fatalf("token = %d, want 101", first)
+	}
+	l.Unlock(ctx)
+
+	// 之后的多数派不包含第一个节点
+	servers[0].Set("res", "someone-else")
+	l, err = c.TryLock(ctx, "res", time.Second)
+	if err != nil || l == nil {
+		t.Fatal(err)
+	}
+	if l.Token() <= first {
+		t.Fatalf("token %d should be greater than %d", l.Token(), first)
+	}
+}
+
+// 过期的 token 无法写入
+func TestFence(t *testing.T) {
+	f := NewFence()
+	if err := f.Check("file", 33); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Check("file", 33); err != nil {
+		t.Fatal("same token should be accepted")
+	}
+	if err := f.Check("file", 34); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Check("file", 33); !errors.Is(err, ErrStaleToken) {
+		t.Fatalf("expected ErrStaleToken, got %v", err)
+	}
+
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+	if err := FencedSet(ctx, rdb, "data", "v34", 34); err != nil {
+		t.Fatal(err)
+	}
+	if err := FencedSet(ctx, rdb, "data", "v33", 33); !errors.Is(err, ErrStaleToken) {
+		t.Fatalf("expected ErrStaleToken, got %v", err)
+	}
+	if v, _ := s.Get("data"); v != "v34" {
+		t.Fatalf("stale write went through: %s", v)
+	}
+}
+
+// clusterSlot 按 Redis Cluster 的规则计算 key 的 slot：有 hash tag 时只用 tag，CRC16 (XMODEM) 取模 16384
+func clusterSlot(key string) int {
+	if i := strings.IndexByte(key, '{'); i >= 0 {
+		if j := strings.IndexByte(key[i+1:], '}'); j > 0 {
+			key = key[i+1 : i+1+j]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for b := 0; b < 8; b++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+// assertSameSlot 同一个脚本操作的 key 必须落在同一个 slot，否则 Redis Cluster 返回 CROSSSLOT
+func assertSameSlot(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, k := range keys[1:] {
+		if clusterSlot(k) != clusterSlot(keys[0]) {
+			t.Errorf("%q (slot %d) and %q (slot %d) are in different slots",
+				keys[0], clusterSlot(keys[0]), k, clusterSlot(k))
+		}
+	}
+}
+
+// fencing 计数器与锁在同一个 Redis Cluster slot
+func TestFencingKeySlot(t *testing.T) {
+	// Redis 文档中的示例值
+	if clusterSlot("foo") != 12182 || clusterSlot("{foo}bar") != 12182 {
+		t.Fatalf("clusterSlot does not match Redis")
+	}
+	cases := map[string]string{
+		"res":           "{res}:fencing",
+		"{user:1}:lock": "{user:1}:lock:fencing",
+	}
+	for key, want := range cases {
+		if got := fencingKey(key); got != want {
+			t.Errorf("fencingKey(%q) = %q, want %q", key, got, want)
+		}
+		assertSameSlot(t, key, fencingKey(key))
+		assertSameSlot(t, key, fenceKey(key))
+	}
+}
+
+// 关闭 fencing 时不创建计数器，Token 为 0
+func TestWithoutFencing(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	l, err := c.TryLockWith(ctx, "res", WithFencing(false))
+	if err != nil || l == nil {
+		t.Fatalf("TryLockWith = %v, %v", l, err)
+	}
+	if l.Token() != 0 {
+		t.Fatalf("Token = %d, want 0", l.Token())
+	}
+	if s.Exists(fencingKey("res")) {
+		t.Fatal("fencing counter should not be created")
+	}
+	if other, err := c.TryLockWith(ctx, "res", WithFencing(false)); err != nil || other != nil {
+		t.Fatalf("lock should still be exclusive: %v, %v", other, err)
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
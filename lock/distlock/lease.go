@@ -0,0 +1,157 @@
+package dlock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// leaseOps 一种租约资源（读锁、写锁、信号量许可）在 Redis 上的操作，都以 token 区分持有者。
+// 多节点（Redlock）模式下这些资源只使用第一个节点
+type leaseOps struct {
+	key     string // 释放通知使用的 key
+	acquire func(ctx context.Context, token string, ttl time.Duration) (bool, error)
+	renew   func(ctx context.Context, token string, ttl time.Duration) (bool, error)
+	release func(ctx context.Context, token string) (bool, error)
+	abandon func(token string) // 放弃等待时的清理，可以为空
+}
+
+// Lease 读写锁、信号量的持有句柄，不可重入。
+// 与 Lock 一样支持自动续约，租约丢失时 Done 关闭、Release 返回 ErrLockLost
+type Lease struct {
+	ops    *leaseOps
+	token  string
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu         sync.Mutex
+	validUntil time.Time
+	released   bool
+	lost       bool
+}
+
+// acquireLease 按 LockOptions 的语义获取租约：wait 为 false 时只尝试一次，拿不到返回 (nil, nil)；
+// 否则在释放通知、兜底轮询之间重试，直到成功、TryTimeout 到达或者 ctx 取消
+func (c *Client) acquireLease(ctx context.Context, ops *leaseOps, cfg LockOptions, wait bool) (*Lease, error) {
+	deadline := cfg.deadline()
+	token := uuid.NewString()
+	cfg.Fair = false
+	w := c.newWaiter(ops.key, token, cfg)
+	defer w.close()
+	giveUp := func() {
+		if ops.abandon != nil {
+			ops.abandon(token)
+		}
+	}
+
+	for {
+		start := time.Now()
+		ok, err := ops.acquire(ctx, token, cfg.TTL)
+		if err != nil {
+			giveUp()
+			return nil, err
+		}
+		if ok {
+			return c.newLease(ops, token, c.validity(cfg.TTL, start), cfg), nil
+		}
+		if !wait {
+			giveUp()
+			return nil, nil
+		}
+
+		if err := w.wait(ctx, deadline); err != nil {
+			giveUp()
+			return nil, err
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			giveUp()
+			return nil, ErrAcquireTimeout
+		}
+	}
+}
+
+// newLease 创建句柄并启动 watchdog
+func (c *Client) newLease(ops *leaseOps, token string, valid time.Duration, cfg LockOptions) *Lease {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	l := &Lease{
+		ops:        ops,
+		token:      token,
+		ctx:        ctx,
+		cancel:     cancel,
+		validUntil: time.Now().Add(valid),
+	}
+	renew := func(ctx context.Context) (bool, time.Time, error) {
+		start := time.Now()
+		ok, err := ops.renew(ctx, token, cfg.TTL)
+		validUntil := start.Add(c.validity(cfg.TTL, start))
+		if ok {
+			l.mu.Lock()
+			l.validUntil = validUntil
+			l.mu.Unlock()
+		}
+		return ok, validUntil, err
+	}
+	validUntil := func() time.Time {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.validUntil
+	}
+	go watch(ctx, validUntil, cfg.renewInterval(), renew, l.lose)
+	return l
+}
+
+// lose 标记租约丢失
+func (l *Lease) lose() {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return
+	}
+	l.lost = true
+	l.mu.Unlock()
+	l.cancel(ErrLockLost)
+}
+
+// Release 释放租约。重复释放返回 ErrNotOwner，释放前已经丢失时返回 ErrLockLost
+func (l *Lease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return ErrNotOwner
+	}
+	l.released = true
+	lost := l.lost
+	l.mu.Unlock()
+	if lost {
+		return ErrLockLost
+	}
+
+	// 停止 watchdog
+	l.cancel(nil)
+	ok, err := l.ops.release(ctx, l.token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Done 返回的 channel 在租约释放或丢失时关闭
+func (l *Lease) Done() <-chan struct{} { return l.ctx.Done() }
+
+// Context 返回持有期间有效的 ctx，租约释放或丢失时取消；丢失时 context.Cause 返回 ErrLockLost
+func (l *Lease) Context() context.Context { return l.ctx }
+
+// ValidUntil 返回租约的有效截止时间，已释放或丢失时返回零值
+func (l *Lease) ValidUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released || l.lost {
+		return time.Time{}
+	}
+	return l.validUntil
+}
@@ -0,0 +1,271 @@
+package dlock
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Locker 与后端无关的分布式锁接口，Client（Redis）、MemoryLocker、SQLLocker 都实现了它。
+// 业务代码依赖 Locker，替换后端时不需要修改调用处；续约与释放通过返回的 *Lock 完成。
+// 所有实现都支持 TTL、TryTimeout、RetryInterval、AutoRenew、RenewInterval、Owner 这些 Option，
+// PubSub、Fair、Fencing 只有 Redis 后端支持，其他后端按 RetryInterval 轮询等待，并且总是生成 fencing token
+type Locker interface {
+	// Lock 获取锁，拿不到时等待，直到成功、TryTimeout 到达（ErrAcquireTimeout）或者 ctx 取消
+	Lock(ctx context.Context, key string, opts ...Option) (*Lock, error)
+	// TryLock 以 ttl 尝试一次获取锁，拿不到时返回 (nil, nil)；ttl <= 0 时使用默认 TTL
+	TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error)
+	// TryLockWith 与 TryLock 相同，但可以指定其他 Option
+	TryLockWith(ctx context.Context, key string, opts ...Option) (*Lock, error)
+}
+
+// Observer 可以查询 key 当前持有者的 Locker，Client、MemoryLocker、SQLLocker 都实现了它
+type Observer interface {
+	// Holder 返回 key 当前持有者的 owner（没有 owner 时为空字符串），没有被持有时 held 为 false
+	Holder(ctx context.Context, key string) (owner string, held bool, err error)
+}
+
+var (
+	_ Locker   = (*Client)(nil)
+	_ Locker   = (*MemoryLocker)(nil)
+	_ Locker   = (*SQLLocker)(nil)
+	_ Observer = (*Client)(nil)
+	_ Observer = (*MemoryLocker)(nil)
+	_ Observer = (*SQLLocker)(nil)
+)
+
+// newToken 生成锁的 value：uuid，有 owner 时追加在后面，便于查询持有者
+func newToken(owner string) string {
+	if owner == "" {
+		return uuid.NewString()
+	}
+	return uuid.NewString() + ":" + owner
+}
+
+// ownerOf 从锁的 value 中解析 owner
+func ownerOf(token string) string {
+	// uuid 固定 36 个字符
+	if len(token) > 37 && token[36] == ':' {
+		return token[37:]
+	}
+	return ""
+}
+
+// backend 锁后端的续约与释放，lockTable 通过它管理本地持有状态
+type backend interface {
+	// renew 续约 token 对应的锁，返回是否成功以及新的有效截止时间
+	renew(ctx context.Context, key, token string, ttl time.Duration) (bool, time.Time, error)
+	// free 释放 token 对应的锁，锁已经不属于 token（过期或被他人持有）时返回 false
+	free(ctx context.Context, key, token string) (bool, error)
+}
+
+// lockTable 本地持有状态：可重入计数、watchdog 与丢锁通知，各个 Locker 实现共用
+type lockTable struct {
+	backend backend
+	metrics Metrics
+
+	mu     sync.Mutex
+	states map[string]*lockState
+}
+
+// newLockTable 创建本地持有状态，m 为空时不上报监控
+func newLockTable(b backend, m Metrics) lockTable {
+	if m == nil {
+		m = nopMetrics{}
+	}
+	return lockTable{backend: b, metrics: m, states: make(map[string]*lockState)}
+}
+
+// store 租约存储的最小操作集合，MemoryLocker、SQLLocker 基于它实现 Locker
+type store interface {
+	// acquire 锁空闲或已过期时以 token 持有 ttl，返回新的 fencing token
+	acquire(ctx context.Context, key, token string, ttl time.Duration) (fencing int64, ok bool, err error)
+	// renew token 仍然持有时把过期时间延长到 ttl 之后
+	renew(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// release token 仍然持有时释放
+	release(ctx context.Context, key, token string) (bool, error)
+}
+
+// storeLocker 基于 store 的 Locker 实现
+type storeLocker struct {
+	lockTable
+	store store
+}
+
+// newStoreLocker 创建基于 store 的 Locker，只使用 ClientOptions 中的 Metrics
+func newStoreLocker(s store, opts []ClientOption) *storeLocker {
+	// 默认配置
+	cfg := DefaultClientOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	l := &storeLocker{store: s}
+	l.lockTable = newLockTable(l, cfg.Metrics)
+	return l
+}
+
+// renew 实现 backend
+func (l *storeLocker) renew(ctx context.Context, key, token string, ttl time.Duration) (bool, time.Time, error) {
+	start := time.Now()
+	ok, err := l.store.renew(ctx, key, token, ttl)
+	return ok, start.Add(ttl), err
+}
+
+// free 实现 backend
+func (l *storeLocker) free(ctx context.Context, key, token string) (bool, error) {
+	return l.store.release(ctx, key, token)
+}
+
+// Lock 实现 Locker
+func (l *storeLocker) Lock(ctx context.Context, key string, opts ...Option) (*Lock, error) {
+	start := time.Now()
+	h, err := l.lock(ctx, key, lockOptions(opts), true)
+	l.metrics.Acquire(key, time.Since(start), h != nil)
+	return h, err
+}
+
+// TryLock 实现 Locker
+func (l *storeLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return l.TryLockWith(ctx, key, WithTTL(ttl))
+}
+
+// TryLockWith 实现 Locker
+func (l *storeLocker) TryLockWith(ctx context.Context, key string, opts ...Option) (*Lock, error) {
+	start := time.Now()
+	h, err := l.lock(ctx, key, lockOptions(opts), false)
+	l.metrics.Acquire(key, time.Since(start), h != nil)
+	return h, err
+}
+
+// lock 加锁逻辑，wait 为 false 时只尝试一次
+func (l *storeLocker) lock(ctx context.Context, key string, cfg LockOptions, wait bool) (*Lock, error) {
+	if cfg.Owner == "" {
+		cfg.Owner = OwnerFromContext(ctx)
+	}
+	deadline := cfg.deadline()
+	token := newToken(cfg.Owner)
+
+	for {
+		// 同一个 owner 已经持有（可重入）
+		if h := l.reenter(key, cfg.Owner); h != nil {
+			return h, nil
+		}
+
+		start := time.Now()
+		fencing, ok, err := l.store.acquire(ctx, key, token, cfg.TTL)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return l.hold(key, cfg.Owner, token, fencing, start.Add(cfg.TTL), cfg.TTL, cfg.renewInterval()), nil
+		}
+		l.metrics.Contention(key)
+		if !wait {
+			return nil, nil
+		}
+
+		// 没拿到锁：睡一会儿再重试（带一点随机抖动）
+		sleep := cfg.RetryInterval
+		sleep += time.Duration(rand.Float64() * jitterFactor * float64(sleep))
+		if !deadline.IsZero() {
+			sleep = min(sleep, time.Until(deadline))
+		}
+		timer := time.NewTimer(sleep)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, ErrAcquireTimeout
+		}
+	}
+}
+
+// MemoryLocker 进程内的 Locker 实现，适合单元测试和单机部署。
+// 语义与 Redis 后端一致：锁带 TTL，token 递增，过期后可以被其他调用获取
+type MemoryLocker struct {
+	*storeLocker
+}
+
+// NewMemoryLocker 创建进程内的 Locker，opts 中只有 Metrics 生效
+func NewMemoryLocker(opts ...ClientOption) *MemoryLocker {
+	return &MemoryLocker{newStoreLocker(&memoryStore{locks: make(map[string]*memoryLock)}, opts)}
+}
+
+// Holder 实现 Observer
+func (l *MemoryLocker) Holder(_ context.Context, key string) (string, bool, error) {
+	s := l.store.(*memoryStore)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.locks[key]; ok && m.token != "" && time.Now().Before(m.expireAt) {
+		return ownerOf(m.token), true, nil
+	}
+	return "", false, nil
+}
+
+// memoryLock 进程内的一把锁
+type memoryLock struct {
+	token    string
+	expireAt time.Time
+	fencing  int64 // 释放后保留，保证 token 单调递增
+}
+
+// memoryStore 进程内的 store
+type memoryStore struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+}
+
+// held 锁是否被 token 持有且没有过期，调用方需持有 mu
+func (s *memoryStore) held(key, token string) (*memoryLock, bool) {
+	l, ok := s.locks[key]
+	if !ok || l.token != token || !time.Now().Before(l.expireAt) {
+		return l, false
+	}
+	return l, true
+}
+
+// acquire 实现 store
+func (s *memoryStore) acquire(_ context.Context, key, token string, ttl time.Duration) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &memoryLock{}
+		s.locks[key] = l
+	} else if l.token != "" && now.Before(l.expireAt) {
+		return 0, false, nil
+	}
+	l.token = token
+	l.expireAt = now.Add(ttl)
+	l.fencing++
+	return l.fencing, true, nil
+}
+
+// renew 实现 store
+func (s *memoryStore) renew(_ context.Context, key, token string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.held(key, token)
+	if ok {
+		l.expireAt = time.Now().Add(ttl)
+	}
+	return ok, nil
+}
+
+// release 实现 store
+func (s *memoryStore) release(_ context.Context, key, token string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.held(key, token)
+	if ok {
+		l.token = ""
+	}
+	return ok, nil
+}
@@ -0,0 +1,114 @@
+package dlock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SQLLockSchema SQLLocker 使用的表结构（SQLite / PostgreSQL 通用），表名可以修改。
+// 释放后保留行以便 fencing 继续递增
+const SQLLockSchema = `CREATE TABLE IF NOT EXISTS dlocks (
+	name       VARCHAR(255) NOT NULL PRIMARY KEY,
+	token      VARCHAR(255) NOT NULL DEFAULT '',
+	fencing    BIGINT       NOT NULL DEFAULT 0,
+	expires_at BIGINT       NOT NULL DEFAULT 0
+)`
+
+// Dialect SQL 方言
+type Dialect int
+
+// 支持的方言
+const (
+	SQLite   Dialect = iota // ? 占位符
+	Postgres                // $1 占位符
+)
+
+// SQLLocker 基于数据库表的 Locker 实现，每把锁一行，expires_at 列（毫秒时间戳）为租约截止时间。
+// 加锁是一条带条件的 upsert：行不存在或租约已过期时才写入自己的 token，
+// 续约、释放都要求 token 匹配且租约没有过期。过期判断使用应用服务器的时钟，各实例之间需要做时钟同步
+type SQLLocker struct {
+	*storeLocker
+}
+
+// NewSQLLocker 创建 SQL 后端，table 为空时使用 dlocks，opts 中只有 Metrics 生效
+func NewSQLLocker(db *sql.DB, table string, d Dialect, opts ...ClientOption) *SQLLocker {
+	if table == "" {
+		table = "dlocks"
+	}
+	p := func(i int) string {
+		if d == Postgres {
+			return fmt.Sprintf("$%d", i)
+		}
+		return "?"
+	}
+	return &SQLLocker{newStoreLocker(&sqlStore{
+		db: db,
+		acquireSQL: fmt.Sprintf(`INSERT INTO %[1]s (name, token, fencing, expires_at) VALUES (%[2]s, %[3]s, 1, %[4]s)
+ON CONFLICT (name) DO UPDATE SET token = excluded.token, fencing = %[1]s.fencing + 1, expires_at = excluded.expires_at
+WHERE %[1]s.expires_at <= %[5]s
+RETURNING fencing`, table, p(1), p(2), p(3), p(4)),
+		renewSQL:   fmt.Sprintf("UPDATE %s SET expires_at = %s WHERE name = %s AND token = %s AND expires_at > %s", table, p(1), p(2), p(3), p(4)),
+		holderSQL:  fmt.Sprintf("SELECT token FROM %s WHERE name = %s AND expires_at > %s", table, p(1), p(2)),
+		releaseSQL: fmt.Sprintf("UPDATE %s SET token = '', expires_at = 0 WHERE name = %s AND token = %s AND expires_at > %s", table, p(1), p(2), p(3)),
+	}, opts)}
+}
+
+// sqlStore 数据库表实现的 store
+type sqlStore struct {
+	db                               *sql.DB
+	acquireSQL, renewSQL, releaseSQL string
+	holderSQL                        string
+}
+
+// Holder 实现 Observer
+func (l *SQLLocker) Holder(ctx context.Context, key string) (string, bool, error) {
+	s := l.store.(*sqlStore)
+	var token string
+	err := s.db.QueryRowContext(ctx, s.holderSQL, key, time.Now().UnixMilli()).Scan(&token)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && token == "") {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return ownerOf(token), true, nil
+}
+
+// acquire 实现 store
+func (s *sqlStore) acquire(ctx context.Context, key, token string, ttl time.Duration) (int64, bool, error) {
+	now := time.Now()
+	var fencing int64
+	err := s.db.QueryRowContext(ctx, s.acquireSQL, key, token, now.Add(ttl).UnixMilli(), now.UnixMilli()).Scan(&fencing)
+	if errors.Is(err, sql.ErrNoRows) {
+		// 冲突且租约没有过期：没有更新任何行
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return fencing, true, nil
+}
+
+// renew 实现 store
+func (s *sqlStore) renew(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	return s.exec(ctx, s.renewSQL, now.Add(ttl).UnixMilli(), key, token, now.UnixMilli())
+}
+
+// release 实现 store
+func (s *sqlStore) release(ctx context.Context, key, token string) (bool, error) {
+	return s.exec(ctx, s.releaseSQL, key, token, time.Now().UnixMilli())
+}
+
+// exec 执行更新语句，返回是否更新了一行
+func (s *sqlStore) exec(ctx context.Context, query string, args ...any) (bool, error) {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,123 @@
+package dlock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	_ "modernc.org/sqlite"
+)
+
+// lockers 每种后端各一个 Locker
+func lockers(t *testing.T) map[string]Locker {
+	t.Helper()
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(SQLLockSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]Locker{
+		"redis":  NewClient(rdb),
+		"memory": NewMemoryLocker(),
+		"sqlite": NewSQLLocker(db, "", SQLite),
+	}
+}
+
+// 各个后端的语义一致：互斥、token 递增、owner 重入、等待超时、释放后可再次获取、查询持有者
+func TestLockerContract(t *testing.T) {
+	for name, locker := range lockers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := NewOwnerContext(context.Background(), "a")
+			other := NewOwnerContext(context.Background(), "b")
+
+			l, err := locker.TryLock(ctx, "res", time.Second)
+			if err != nil || l == nil {
+				t.Fatalf("TryLock = %v, %v", l, err)
+			}
+			first := l.Token()
+			if owner, held, err := locker.(Observer).Holder(ctx, "res"); err != nil || !held || owner != "a" {
+				t.Fatalf("Holder = %q, %v, %v", owner, held, err)
+			}
+			if o, err := locker.TryLockWith(other, "res"); err != nil || o != nil {
+				t.Fatalf("other owner TryLock = %v, %v", o, err)
+			}
+			_, err = locker.Lock(other, "res", WithTryTimeout(50*time.Millisecond), WithRetryInterval(10*time.Millisecond))
+			if !errors.Is(err, ErrAcquireTimeout) {
+				t.Fatalf("expected ErrAcquireTimeout, got %v", err)
+			}
+			re, err := locker.Lock(ctx, "res")
+			if err != nil || re.Token() != first {
+				t.Fatalf("reentrant Lock = %v, %v", re, err)
+			}
+			re.Unlock(ctx)
+			if err := l.Renew(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			// 释放时唤醒另一个等待者
+			go func() {
+				time.Sleep(30 * time.Millisecond)
+				l.Unlock(ctx)
+			}()
+			l2, err := locker.Lock(other, "res", WithTryTimeout(time.Second), WithRetryInterval(10*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l2.Token() <= first {
+				t.Fatalf("token %d not greater than %d", l2.Token(), first)
+			}
+			if err := l2.Unlock(other); err != nil {
+				t.Fatal(err)
+			}
+			if err := l2.Unlock(other); !errors.Is(err, ErrNotOwner) {
+				t.Fatalf("expected ErrNotOwner, got %v", err)
+			}
+			if _, held, err := locker.(Observer).Holder(ctx, "res"); err != nil || held {
+				t.Fatalf("released lock should not be held: %v, %v", held, err)
+			}
+		})
+	}
+}
+
+// 各个后端在 TTL 之后都会丢锁，自动续约可以保持持有
+func TestLockerLease(t *testing.T) {
+	for name, locker := range lockers(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			l, err := locker.Lock(ctx, "expire", WithTTL(50*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-l.Done()
+			if err := l.Unlock(ctx); !errors.Is(err, ErrLockLost) {
+				t.Fatalf("expected ErrLockLost, got %v", err)
+			}
+
+			l, err = locker.Lock(ctx, "renew", WithTTL(100*time.Millisecond), WithAutoRenew(true), WithRenewInterval(20*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(250 * time.Millisecond)
+			if l.Context().Err() != nil {
+				t.Fatal("auto-renewed lock should still be held")
+			}
+			if err := l.Unlock(ctx); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
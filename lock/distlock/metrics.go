@@ -0,0 +1,82 @@
+package dlock
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics 锁事件的监控钩子，通过 WithMetrics 注册。实现需要并发安全且尽快返回。
+// 只覆盖 Locker（Lock、TryLock）获取的锁，读写锁和信号量不上报
+type Metrics interface {
+	// Acquire 一次 Lock 或 TryLock 结束，latency 为总耗时（包含等待），acquired 表示是否拿到锁
+	Acquire(key string, latency time.Duration, acquired bool)
+	// Contention 一次加锁尝试发现锁被其他持有者占用
+	Contention(key string)
+	// RenewFailure 一次续约失败，err 为 nil 表示锁已经不属于自己
+	RenewFailure(key string, err error)
+	// Lost 持有期间丢锁
+	Lost(key string)
+}
+
+// nopMetrics 不上报
+type nopMetrics struct{}
+
+func (nopMetrics) Acquire(string, time.Duration, bool) {}
+func (nopMetrics) Contention(string)                   {}
+func (nopMetrics) RenewFailure(string, error)          {}
+func (nopMetrics) Lost(string)                         {}
+
+// CounterStats Counters 的快照
+type CounterStats struct {
+	Acquires        uint64        // 成功拿到锁的次数
+	AcquireFailures uint64        // 没拿到锁的次数（TryLock 失败、超时、ctx 取消、出错）
+	AcquireTime     time.Duration // 所有 Lock、TryLock 的总耗时
+	Contentions     uint64        // 加锁尝试遇到锁被占用的次数
+	RenewFailures   uint64        // 续约失败次数
+	Losses          uint64        // 丢锁次数
+}
+
+// AverageAcquireTime 平均每次 Lock、TryLock 的耗时
+func (s CounterStats) AverageAcquireTime() time.Duration {
+	if n := s.Acquires + s.AcquireFailures; n > 0 {
+		return s.AcquireTime / time.Duration(n)
+	}
+	return 0
+}
+
+// Counters 按事件计数的 Metrics 实现，不区分 key，可以定期读取 Stats 导出到监控系统
+type Counters struct {
+	acquires, failures, contentions, renewFailures, losses atomic.Uint64
+	nanos                                                  atomic.Int64
+}
+
+// Acquire 实现 Metrics
+func (c *Counters) Acquire(_ string, latency time.Duration, acquired bool) {
+	if acquired {
+		c.acquires.Add(1)
+	} else {
+		c.failures.Add(1)
+	}
+	c.nanos.Add(int64(latency))
+}
+
+// Contention 实现 Metrics
+func (c *Counters) Contention(string) { c.contentions.Add(1) }
+
+// RenewFailure 实现 Metrics
+func (c *Counters) RenewFailure(string, error) { c.renewFailures.Add(1) }
+
+// Lost 实现 Metrics
+func (c *Counters) Lost(string) { c.losses.Add(1) }
+
+// Stats 返回当前的计数
+func (c *Counters) Stats() CounterStats {
+	return CounterStats{
+		Acquires:        c.acquires.Load(),
+		AcquireFailures: c.failures.Load(),
+		AcquireTime:     time.Duration(c.nanos.Load()),
+		Contentions:     c.contentions.Load(),
+		RenewFailures:   c.renewFailures.Load(),
+		Losses:          c.losses.Load(),
+	}
+}
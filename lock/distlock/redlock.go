@@ -0,0 +1,259 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClientOptions 控制 Client 行为
+type ClientOptions struct {
+	// 以下只在多节点（Redlock）模式下生效
+	DriftFactor float64       // 时钟漂移系数，有效期会扣除 TTL*DriftFactor + 2ms（默认 0.01）
+	NodeTimeout time.Duration // 单个节点一次请求的超时，应远小于 TTL，避免等待宕机节点耗尽有效期（默认 50ms）
+
+	Metrics Metrics // 加锁耗时、竞争、续约失败等事件的监控钩子（默认不上报）
+}
+
+// 一些默认值
+const (
+	defaultDriftFactor = 0.01
+	defaultNodeTimeout = 50 * time.Millisecond
+	driftConstant      = 2 * time.Millisecond // 各节点过期时间的精度误差
+)
+
+// DefaultClientOptions 默认配置
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		DriftFactor: defaultDriftFactor,
+		NodeTimeout: defaultNodeTimeout,
+	}
+}
+
+// ClientOption 函数式编程
+type ClientOption func(*ClientOptions)
+
+// WithDriftFactor 初始化 DriftFactor
+func WithDriftFactor(f float64) ClientOption {
+	return func(o *ClientOptions) {
+		o.DriftFactor = f
+	}
+}
+
+// WithNodeTimeout 初始化 NodeTimeout
+func WithNodeTimeout(d time.Duration) ClientOption {
+	return func(o *ClientOptions) {
+		o.NodeTimeout = d
+	}
+}
+
+// WithMetrics 初始化 Metrics
+func WithMetrics(m Metrics) ClientOption {
+	return func(o *ClientOptions) {
+		o.Metrics = m
+	}
+}
+
+// NewRedlockClient 使用 N 个相互独立（非主从）的 Redis 实例创建 Redlock 模式的客户端。
+// 加锁需要在多数节点（N/2+1）上成功，且扣除耗时与时钟漂移后仍有剩余有效期；
+// 续约同样需要多数节点成功；释放会并发发往所有节点。建议 N 取 5 这样的奇数。
+func NewRedlockClient(nodes []*redis.Client, opts ...ClientOption) *Client {
+	if len(nodes) == 0 {
+		panic("dlock: NewRedlockClient requires at least one redis client")
+	}
+	return newClient(nodes, opts)
+}
+
+// newClient 合并配置并创建 Client
+func newClient(nodes []*redis.Client, opts []ClientOption) *Client {
+	// 默认配置
+	cfg := DefaultClientOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	if cfg.DriftFactor < 0 {
+		cfg.DriftFactor = defaultDriftFactor
+	}
+	c := &Client{
+		nodes:  nodes,
+		quorum: len(nodes)/2 + 1,
+		cfg:    cfg,
+	}
+	c.lockTable = newLockTable(c, cfg.Metrics)
+	return c
+}
+
+// nodeResult 一次多节点操作的结果
+type nodeResult struct {
+	ok     int   // fn 返回 true 的节点数
+	failed int   // 出错的节点数
+	err    error // 所有节点错误的合并
+}
+
+// each 在所有节点上并发执行 fn 并汇总结果。
+// 多节点模式下每个节点的请求受 NodeTimeout 限制；单节点时直接使用 ctx，与普通 Redis 客户端行为一致。
+func (c *Client) each(ctx context.Context, fn func(ctx context.Context, rdb *redis.Client) (bool, error)) nodeResult {
+	if len(c.nodes) == 1 {
+		ok, err := fn(ctx, c.nodes[0])
+		switch {
+		case err != nil:
+			return nodeResult{failed: 1, err: err}
+		case ok:
+			return nodeResult{ok: 1}
+		default:
+			return nodeResult{}
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		res  nodeResult
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for _, rdb := range c.nodes {
+		wg.Add(1)
+		go func(rdb *redis.Client) {
+			defer wg.Done()
+			nctx := ctx
+			if c.cfg.NodeTimeout > 0 {
+				var cancel context.CancelFunc
+				nctx, cancel = context.WithTimeout(ctx, c.cfg.NodeTimeout)
+				defer cancel()
+			}
+			ok, err := fn(nctx, rdb)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				res.failed++
+				errs = append(errs, fmt.Errorf("%s: %w", rdb.Options().Addr, err))
+			case ok:
+				res.ok++
+			}
+		}(rdb)
+	}
+	wg.Wait()
+	res.err = errors.Join(errs...)
+	return res
+}
+
+// quorumErr 没有达到多数时，如果是因为出错的节点太多（而不是锁被别人持有），返回这些错误
+func (c *Client) quorumErr(res nodeResult) error {
+	if res.ok >= c.quorum || len(c.nodes)-res.failed >= c.quorum {
+		return nil
+	}
+	return res.err
+}
+
+// validity 扣除耗时与时钟漂移后锁的剩余有效期
+func (c *Client) validity(ttl time.Duration, start time.Time) time.Duration {
+	if len(c.nodes) == 1 {
+		return ttl - time.Since(start)
+	}
+	drift := time.Duration(float64(ttl)*c.cfg.DriftFactor) + driftConstant
+	return ttl - time.Since(start) - drift
+}
+
+// acquire 在所有节点上尝试 SET NX 并生成 fencing token，多数成功且仍有有效期时返回 true、
+// fencing token 和锁的有效截止时间；否则释放已经拿到的节点并返回 false。只有错误导致无法达到多数时才返回 error。
+// withFencing 为 false 时不生成 fencing token（返回 0）
+func (c *Client) acquire(ctx context.Context, key, token string, ttl time.Duration, withFencing bool) (bool, int64, time.Time, error) {
+	start := time.Now()
+	var (
+		mu      sync.Mutex
+		fencing int64
+	)
+	res := c.each(ctx, func(ctx context.Context, rdb *redis.Client) (bool, error) {
+		if !withFencing {
+			n, err := acquireNoFencingScript.Run(ctx, rdb, []string{key}, token, ttl.Milliseconds()).Int()
+			return n == 1, err
+		}
+		n, err := acquireScript.Run(ctx, rdb, []string{key, fencingKey(key)}, token, ttl.Milliseconds()).Int64()
+		if err != nil || n == 0 {
+			return false, err
+		}
+		mu.Lock()
+		fencing = max(fencing, n)
+		mu.Unlock()
+		return true, nil
+	})
+	if res.ok >= c.quorum && len(c.nodes) > 1 && withFencing {
+		// 各节点的计数器互不相同，取最大值后再把多数节点的计数器抬到这个值：
+		// 之后任何一次加锁的多数派都至少包含一个已抬高的节点，拿到的 token 一定更大
+		raised := c.each(ctx, func(ctx context.Context, rdb *redis.Client) (bool, error) {
+			return true, raiseScript.Run(ctx, rdb, []string{fencingKey(key)}, fencing).Err()
+		})
+		res.ok = min(res.ok, raised.ok)
+	}
+	if valid := c.validity(ttl, start); res.ok >= c.quorum && valid > 0 {
+		return true, fencing, start.Add(valid), nil
+	}
+	if res.ok > 0 || (len(c.nodes) > 1 && res.failed > 0) {
+		// 没达到多数：把可能已经拿到的部分释放掉（超时的节点上也可能写入成功），避免其他客户端等到过期
+		c.release(context.WithoutCancel(ctx), key, token)
+	}
+	return false, 0, time.Time{}, c.quorumErr(res)
+}
+
+// release 在所有节点上删除 token 匹配的锁
+func (c *Client) release(ctx context.Context, key, token string) nodeResult {
+	return c.each(ctx, func(ctx context.Context, rdb *redis.Client) (bool, error) {
+		res, err := unlockScript.Run(ctx, rdb, []string{key}, token, releaseChannel(key)).Int()
+		return res == 1, err
+	})
+}
+
+// free 实现 backend：在所有节点上释放，没有在多数节点上删除时返回 false
+func (c *Client) free(ctx context.Context, key, token string) (bool, error) {
+	res := c.release(ctx, key, token)
+	if err := c.quorumErr(res); err != nil {
+		return false, err
+	}
+	return res.ok >= c.quorum, nil
+}
+
+// Holder 实现 Observer：多数节点上的 value 一致时才认为 key 被持有
+func (c *Client) Holder(ctx context.Context, key string) (string, bool, error) {
+	var (
+		mu     sync.Mutex
+		values = make(map[string]int)
+	)
+	res := c.each(ctx, func(ctx context.Context, rdb *redis.Client) (bool, error) {
+		v, err := rdb.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		mu.Lock()
+		values[v]++
+		mu.Unlock()
+		return true, nil
+	})
+	for v, n := range values {
+		if n >= c.quorum {
+			return ownerOf(v), true, nil
+		}
+	}
+	return "", false, c.quorumErr(res)
+}
+
+// renew 在所有节点上续约，多数成功且仍有有效期时返回 true 和新的有效截止时间
+func (c *Client) renew(ctx context.Context, key, token string, ttl time.Duration) (bool, time.Time, error) {
+	start := time.Now()
+	res := c.each(ctx, func(ctx context.Context, rdb *redis.Client) (bool, error) {
+		n, err := renewScript.Run(ctx, rdb, []string{key}, token, ttl.Milliseconds()).Int()
+		return n == 1, err
+	})
+	if valid := c.validity(ttl, start); res.ok >= c.quorum && valid > 0 {
+		return true, start.Add(valid), nil
+	}
+	return false, time.Time{}, c.quorumErr(res)
+}
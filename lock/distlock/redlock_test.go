@@ -0,0 +1,166 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newNodes 启动 n 个相互独立的进程内 Redis
+func newNodes(t *testing.T, n int) ([]*miniredis.Miniredis, []*redis.Client) {
+	t.Helper()
+	servers := make([]*miniredis.Miniredis, n)
+	clients := make([]*redis.Client, n)
+	for i := range servers {
+		servers[i] = miniredis.RunT(t)
+		clients[i] = redis.NewClient(&redis.Options{Addr: servers[i].Addr(), MaxRetries: -1})
+		t.Cleanup(func() { clients[i].Close() })
+	}
+	return servers, clients
+}
+
+// 多数节点加锁成功即可持有，释放会删除所有节点上的 key
+func TestRedlockAcquireRelease(t *testing.T) {
+	servers, clients := newNodes(t, 5)
+	c1 := NewRedlockClient(clients)
+	c2 := NewRedlockClient(clients)
+	ctx := context.Background()
+
+	start := time.Now()
+	l, err := c1.TryLock(ctx, "res", time.Second)
+	if err != nil || l == nil {
+		t.Fatalf("TryLock = %v, %v", l, err)
+	}
+	// 有效期扣除了漂移：1s*0.01 + 2ms
+	if v := l.ValidUntil(); !v.Before(start.Add(time.Second-12*time.Millisecond)) || v.Before(start) {
+		t.Fatalf("unexpected validity %v", v.Sub(start))
+	}
+	for i, s := range servers {
+		if !s.Exists("res") {
+			t.Fatalf("node %d has no key", i)
+		}
+	}
+
+	if l2, err := c2.TryLock(ctx, "res", time.Second); err != nil || l2 != nil {
+		t.Fatalf("second client should not acquire: %v, %v", l2, err)
+	}
+
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range servers {
+		if s.Exists("res") {
+			t.Fatalf("node %d still has the key", i)
+		}
+	}
+}
+
+// 少数节点宕机不影响加锁，多数节点宕机时返回错误
+func TestRedlockNodeFailures(t *testing.T) {
+	servers, clients := newNodes(t, 5)
+	c := NewRedlockClient(clients, WithNodeTimeout(100*time.Millisecond))
+	ctx := context.Background()
+
+	servers[0].Close()
+	servers[1].Close()
+	l, err := c.TryLock(ctx, "res", time.Second)
+	if err != nil || l == nil {
+		t.Fatalf("3/5 nodes alive should acquire: %v, %v", l, err)
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("unlock with quorum should succeed: %v", err)
+	}
+
+	servers[2].Close()
+	if _, err := c.TryLock(ctx, "res", time.Second); err == nil {
+		t.Fatal("2/5 nodes alive should return an error")
+	}
+}
+
+// 没有达到多数时释放已经拿到的节点
+func TestRedlockPartialAcquireRollsBack(t *testing.T) {
+	servers, clients := newNodes(t, 5)
+	for _, s := range servers[:3] {
+		s.Set("res", "someone-else")
+	}
+	c := NewRedlockClient(clients)
+	l, err := c.TryLock(context.Background(), "res", time.Second)
+	if err != nil || l != nil {
+		t.Fatalf("TryLock = %v, %v", l, err)
+	}
+	for i, s := range servers[3:] {
+		if s.Exists("res") {
+			t.Fatalf("node %d should have been rolled back", i+3)
+		}
+	}
+
+	// 带重试的 Lock 超时返回 ErrAcquireTimeout
+	_, err = c.Lock(context.Background(), "res", WithTTL(time.Second), WithTryTimeout(50*time.Millisecond), WithRetryInterval(10*time.Millisecond))
+	if !errors.Is(err, ErrAcquireTimeout) {
+		t.Fatalf("expected ErrAcquireTimeout, got %v", err)
+	}
+}
+
+// 自动续约需要多数节点成功；锁在多数节点上丢失后 Unlock 返回 ErrLockLost
+func TestRedlockRenewQuorum(t *testing.T) {
+	servers, clients := newNodes(t, 3)
+	c := NewRedlockClient(clients)
+	ctx := context.Background()
+
+	l, err := c.Lock(ctx, "res", WithTTL(300*time.Millisecond), WithAutoRenew(true), WithRenewInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := l.ValidUntil()
+	for _, s := range servers {
+		s.FastForward(200 * time.Millisecond)
+	}
+	time.Sleep(60 * time.Millisecond)
+	for i, s := range servers {
+		if ttl := s.TTL("res"); ttl < 250*time.Millisecond {
+			t.Fatalf("node %d not renewed, ttl %v", i, ttl)
+		}
+	}
+	if !l.ValidUntil().After(first) {
+		t.Fatal("validity should be extended after renewal")
+	}
+
+	// 两个节点上的锁被删除，续约达不到多数，释放也达不到多数
+	servers[0].Del("res")
+	servers[1].Del("res")
+	if err := l.Unlock(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+}
+
+// 单节点模式保持原有语义
+func TestSingleNodeClient(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	l, err := c.Lock(ctx, "res", WithTTL(time.Second), WithOwner("worker-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2, err := c.Lock(ctx, "res", WithOwner("worker-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2.Unlock(ctx)
+	if !s.Exists("res") {
+		t.Fatal("reentrant unlock should keep the key")
+	}
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(ctx); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected ErrNotOwner, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package dlock
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// serverNow lua 片段：以 Redis 服务器的时间（毫秒）作为 now，避免各个客户端的时钟偏差
+// 导致提前清理他人的租约或者租约超期存活
+const serverNow = `
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+`
+
+// lua 脚本：加读锁。有写锁或者有写者在等待（写优先）时失败；
+// 否则清理过期的读者，把 ARGV[1] 加入读者集合，score 为租约截止时间（毫秒）
+var readLockScript = redis.NewScript(serverNow + `
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("EXISTS", KEYS[3]) == 1 then
+  return 0
+end
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", now)
+redis.call("ZADD", KEYS[2], now + tonumber(ARGV[2]), ARGV[1])
+if redis.call("PTTL", KEYS[2]) < tonumber(ARGV[2]) then
+  redis.call("PEXPIRE", KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// lua 脚本：续约读锁。租约已经过期（即使还没有被清理）时不能续约，许可可能已经被其他人占用
+var readRenewScript = redis.NewScript(serverNow + `
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not score then
+  return 0
+end
+if tonumber(score) <= now then
+  redis.call("ZREM", KEYS[1], ARGV[1])
+  return 0
+end
+redis.call("ZADD", KEYS[1], "XX", now + tonumber(ARGV[2]), ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+  redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// lua 脚本：释放读锁，释放后在 ARGV[2] 上发布通知
+var readUnlockScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+  return 0
+end
+redis.call("PUBLISH", ARGV[2], "1")
+return 1
+`)
+
+// lua 脚本：加写锁。没有写锁且没有未过期的读者时成功，并清除自己的等待标记；
+// 否则登记写等待标记（PX ARGV[3]），阻止新的读者进入，避免写者饥饿
+var writeLockScript = redis.NewScript(serverNow + `
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", now)
+if redis.call("EXISTS", KEYS[1]) == 0 and redis.call("ZCARD", KEYS[2]) == 0 then
+  redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+  if redis.call("GET", KEYS[3]) == ARGV[1] then
+    redis.call("DEL", KEYS[3])
+  end
+  return 1
+end
+local waiting = redis.call("GET", KEYS[3])
+if not waiting or waiting == ARGV[1] then
+  redis.call("SET", KEYS[3], ARGV[1], "PX", ARGV[3])
+end
+return 0
+`)
+
+// RWLock 分布式读写锁：同一时间可以有多个读者或者一个写者。
+// 写优先：有写者在等待时新的读者不能进入，已有的读者释放后写者获取。
+// 读锁、写锁都是带租约的，复用 Lock 的 TTL、TryTimeout、AutoRenew、PubSub 等 Option（Owner、Fair 不生效）。
+// Redis 中使用 {key}:write（写锁）、{key}:readers（读者 ZSET）、{key}:writer-waiting（写等待标记）三个 key
+type RWLock struct {
+	c   *Client
+	cfg LockOptions
+
+	read, write *leaseOps
+}
+
+// 读写锁使用的 key：写锁、读者 ZSET、写等待标记，在同一个 slot，读锁和写锁的脚本同时操作它们
+func rwKeys(key string) []string {
+	return []string{siblingKey(key, ":write"), siblingKey(key, ":readers"), siblingKey(key, ":writer-waiting")}
+}
+
+// NewRWLock 创建读写锁，多节点（Redlock）模式下只使用第一个节点
+func (c *Client) NewRWLock(key string, opts ...Option) *RWLock {
+	cfg := lockOptions(opts)
+	rdb := c.nodes[0]
+	keys := rwKeys(key)
+	channel := releaseChannel(key)
+	// 写等待标记需要在写者两次尝试之间保持有效
+	waitTTL := 3*max(cfg.FallbackInterval, cfg.RetryInterval) + c.cfg.NodeTimeout
+
+	rw := &RWLock{c: c, cfg: cfg}
+	rw.read = &leaseOps{
+		key: key,
+		acquire: func(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+			n, err := readLockScript.Run(ctx, rdb, keys, token, ttl.Milliseconds()).Int()
+			return n == 1, err
+		},
+		renew: func(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+			n, err := readRenewScript.Run(ctx, rdb, keys[1:2], token, ttl.Milliseconds()).Int()
+			return n == 1, err
+		},
+		release: func(ctx context.Context, token string) (bool, error) {
+			n, err := readUnlockScript.Run(ctx, rdb, keys[1:2], token, channel).Int()
+			return n == 1, err
+		},
+	}
+	rw.write = &leaseOps{
+		key: key,
+		acquire: func(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+			n, err := writeLockScript.Run(ctx, rdb, keys, token, ttl.Milliseconds(), waitTTL.Milliseconds()).Int()
+			return n == 1, err
+		},
+		renew: func(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+			n, err := renewScript.Run(ctx, rdb, keys[:1], token, ttl.Milliseconds()).Int()
+			return n == 1, err
+		},
+		release: func(ctx context.Context, token string) (bool, error) {
+			n, err := unlockScript.Run(ctx, rdb, keys[:1], token, channel).Int()
+			return n == 1, err
+		},
+		abandon: func(token string) {
+			// 清除自己的写等待标记，唤醒被挡住的读者
+			ctx, cancel := context.WithTimeout(context.Background(), max(c.cfg.NodeTimeout, defaultNodeTimeout))
+			defer cancel()
+			unlockScript.Run(ctx, rdb, keys[2:], token, channel)
+		},
+	}
+	return rw
+}
+
+// RLock 获取读锁，等待语义与 Client.Lock 相同
+func (rw *RWLock) RLock(ctx context.Context) (*Lease, error) {
+	return rw.c.acquireLease(ctx, rw.read, rw.cfg, true)
+}
+
+// TryRLock 尝试一次获取读锁，拿不到时返回 (nil, nil)
+func (rw *RWLock) TryRLock(ctx context.Context) (*Lease, error) {
+	return rw.c.acquireLease(ctx, rw.read, rw.cfg, false)
+}
+
+// Lock 获取写锁，等待期间会阻止新的读者进入
+func (rw *RWLock) Lock(ctx context.Context) (*Lease, error) {
+	return rw.c.acquireLease(ctx, rw.write, rw.cfg, true)
+}
+
+// TryLock 尝试一次获取写锁，拿不到时返回 (nil, nil)
+func (rw *RWLock) TryLock(ctx context.Context) (*Lease, error) {
+	return rw.c.acquireLease(ctx, rw.write, rw.cfg, false)
+}
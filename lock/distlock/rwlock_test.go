@@ -0,0 +1,121 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 多个读者可以同时持有，写者与读者互斥
+func TestRWLockReaders(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	rw := NewClient(rdb).NewRWLock("res")
+	ctx := context.Background()
+
+	r1, err := rw.RLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := rw.TryRLock(ctx)
+	if err != nil || r2 == nil {
+		t.Fatalf("second reader = %v, %v", r2, err)
+	}
+	if w, err := rw.TryLock(ctx); err != nil || w != nil {
+		t.Fatalf("writer should fail while readers hold: %v, %v", w, err)
+	}
+	// TryLock 失败后不会留下写等待标记
+	if r3, err := rw.TryRLock(ctx); err != nil || r3 == nil {
+		t.Fatalf("reader after failed TryLock = %v, %v", r3, err)
+	} else {
+		r3.Release(ctx)
+	}
+
+	r1.Release(ctx)
+	r2.Release(ctx)
+	w, err := rw.TryLock(ctx)
+	if err != nil || w == nil {
+		t.Fatalf("writer after readers released = %v, %v", w, err)
+	}
+	if r, err := rw.TryRLock(ctx); err != nil || r != nil {
+		t.Fatalf("reader should fail while writer holds: %v, %v", r, err)
+	}
+	if err := w.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Release(ctx); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("expected ErrNotOwner, got %v", err)
+	}
+}
+
+// 有写者在等待时新的读者不能进入，读者释放后写者被唤醒
+func TestRWLockWriterPreference(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	rw := NewClient(rdb).NewRWLock("res", WithTryTimeout(5*time.Second))
+	ctx := context.Background()
+	assertSameSlot(t, rwKeys("res")...)
+
+	r, err := rw.RLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan *Lease)
+	go func() {
+		w, err := rw.Lock(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- w
+	}()
+	for !s.Exists(rwKeys("res")[2]) {
+		time.Sleep(time.Millisecond)
+	}
+	if r2, err := rw.TryRLock(ctx); err != nil || r2 != nil {
+		t.Fatalf("reader should wait behind the writer: %v, %v", r2, err)
+	}
+
+	r.Release(ctx)
+	select {
+	case w := <-got:
+		if s.Exists(rwKeys("res")[2]) {
+			t.Fatal("writer should clear its waiting mark")
+		}
+		w.Release(ctx)
+	case <-time.After(time.Second):
+		t.Fatal("writer should be woken after the reader releases")
+	}
+}
+
+// 没有续约的读锁过期后视为丢失
+func TestRWLockLeaseLost(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	rw := NewClient(rdb).NewRWLock("res", WithTTL(50*time.Millisecond))
+	ctx := context.Background()
+
+	r, err := rw.RLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lease should be lost after TTL")
+	}
+	if err := r.Release(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+	// 过期的读者不会挡住写者。服务器按自己的时间记录租约，比本地的有效截止时间稍晚一点
+	time.Sleep(10 * time.Millisecond)
+	if w, err := rw.TryLock(ctx); err != nil || w == nil {
+		t.Fatalf("writer after reader expired = %v, %v", w, err)
+	}
+}
@@ -0,0 +1,111 @@
+package dlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 最多 permits 个持有者，释放后等待者被唤醒
+func TestSemaphore(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	sem := c.NewSemaphore("downstream", 2, WithTryTimeout(5*time.Second))
+	ctx := context.Background()
+
+	a, err := sem.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.NewSemaphore("downstream", 2).TryAcquire(ctx)
+	if err != nil || b == nil {
+		t.Fatalf("second permit = %v, %v", b, err)
+	}
+	if p, err := sem.TryAcquire(ctx); err != nil || p != nil {
+		t.Fatalf("third permit should fail: %v, %v", p, err)
+	}
+
+	got := make(chan *Lease)
+	go func() {
+		p, err := sem.Acquire(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- p
+	}()
+	time.Sleep(20 * time.Millisecond)
+	a.Release(ctx)
+	select {
+	case p := <-got:
+		p.Release(ctx)
+	case <-time.After(time.Second):
+		t.Fatal("waiter should be woken after a permit is released")
+	}
+	b.Release(ctx)
+}
+
+// 自动续约的许可不会被回收，崩溃（不续约也不释放）的许可在 TTL 后回收
+func TestSemaphoreLease(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	renewed := c.NewSemaphore("renewed", 1, WithTTL(100*time.Millisecond), WithAutoRenew(true), WithRenewInterval(20*time.Millisecond))
+	p, err := renewed.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if q, err := renewed.TryAcquire(ctx); err != nil || q != nil {
+		t.Fatalf("renewed permit should still be held: %v, %v", q, err)
+	}
+	if err := p.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	crashed := c.NewSemaphore("crashed", 1, WithTTL(50*time.Millisecond))
+	if _, err := crashed.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	if q, err := crashed.TryAcquire(ctx); err != nil || q == nil {
+		t.Fatalf("expired permit should be reclaimed: %v, %v", q, err)
+	}
+}
+
+// 租约按 Redis 服务器时间计算：过期与否不受客户端时钟影响，过期但还没清理的许可不能续约
+func TestSemaphoreServerTime(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+	sem := c.NewSemaphore("clock", 1, WithTTL(time.Minute))
+
+	// 服务器时间在客户端之前很久，客户端时钟超前也不会提前清理有效的许可
+	base := time.Now().Add(-time.Hour)
+	s.SetTime(base)
+	p, err := sem.TryAcquire(ctx)
+	if err != nil || p == nil {
+		t.Fatalf("TryAcquire = %v, %v", p, err)
+	}
+	if other, err := sem.TryAcquire(ctx); err != nil || other != nil {
+		t.Fatalf("live permit should not be reaped: %v, %v", other, err)
+	}
+
+	// 服务器时间越过租约截止时间后，旧的许可不能再续约
+	s.SetTime(base.Add(2 * time.Minute))
+	if ok, err := sem.ops.renew(ctx, p.token, time.Minute); err != nil || ok {
+		t.Fatalf("expired permit should not be renewed: %v, %v", ok, err)
+	}
+	if other, err := sem.TryAcquire(ctx); err != nil || other == nil {
+		t.Fatalf("expired permit should be reclaimed: %v, %v", other, err)
+	}
+}
@@ -0,0 +1,170 @@
+package dlock
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// releaseChannel 锁释放时发布通知的 channel
+func releaseChannel(key string) string {
+	return key + ":released"
+}
+
+// 公平模式使用的 key：排队顺序、等待者心跳截止时间、到达序号，与锁在同一个 slot
+func queueKeys(key string) []string {
+	return []string{siblingKey(key, ":queue"), siblingKey(key, ":queue:timeout"), siblingKey(key, ":queue:seq")}
+}
+
+// lua 脚本：清理心跳超时的等待者，把 ARGV[1] 加入队尾（已在队列中则只刷新心跳），是队头时返回 1。
+// 有等待者被清理时在 ARGV[4] 上发布通知，唤醒新的队头。
+// 队列的 key 至少保留一个心跳超时 ARGV[3]，所有等待者都停止心跳后自动过期，不会留下到达序号
+var queueScript = redis.NewScript(`
+local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[2])
+for _, t in ipairs(expired) do
+  redis.call("ZREM", KEYS[1], t)
+  redis.call("ZREM", KEYS[2], t)
+end
+if #expired > 0 then
+  redis.call("PUBLISH", ARGV[4], "1")
+end
+if not redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+  redis.call("ZADD", KEYS[1], redis.call("INCR", KEYS[3]), ARGV[1])
+end
+redis.call("ZADD", KEYS[2], tonumber(ARGV[2]) + tonumber(ARGV[3]), ARGV[1])
+for i = 1, 3 do
+  if redis.call("PTTL", KEYS[i]) < tonumber(ARGV[3]) then
+    redis.call("PEXPIRE", KEYS[i], ARGV[3])
+  end
+end
+if redis.call("ZRANGE", KEYS[1], 0, 0)[1] == ARGV[1] then
+  return 1
+end
+return 0
+`)
+
+// lua 脚本：离开队列，队列空了时删除到达序号；ARGV[3] 为 1 时（放弃等待）发布通知唤醒下一个等待者
+var leaveScript = redis.NewScript(`
+redis.call("ZREM", KEYS[1], ARGV[1])
+redis.call("ZREM", KEYS[2], ARGV[1])
+if redis.call("ZCARD", KEYS[1]) == 0 then
+  redis.call("DEL", KEYS[3])
+end
+if ARGV[3] == "1" then
+  redis.call("PUBLISH", ARGV[2], "1")
+end
+return 1
+`)
+
+// waiter 一次 Lock 调用的等待状态：释放通知的订阅与公平队列中的位置
+type waiter struct {
+	c        *Client
+	key      string
+	token    string
+	cfg      LockOptions
+	acquired bool // 是否已经拿到锁
+
+	notify chan struct{}
+	subs   []*redis.PubSub
+	queued bool // 是否已经进入公平队列
+}
+
+// newWaiter 创建等待状态，订阅在第一次需要等待时才建立
+func (c *Client) newWaiter(key, token string, cfg LockOptions) *waiter {
+	return &waiter{c: c, key: key, token: token, cfg: cfg, notify: make(chan struct{}, 1)}
+}
+
+// interval 两次尝试之间最长的等待时间
+func (w *waiter) interval() time.Duration {
+	if w.cfg.PubSub {
+		return w.cfg.FallbackInterval
+	}
+	return w.cfg.RetryInterval
+}
+
+// turn 公平模式下排队并刷新心跳，返回是否轮到自己；非公平模式总是返回 true。
+// 队列保存在第一个节点上，等待者超过三个等待间隔没有刷新心跳就会被移出队列，避免崩溃的进程堵住队列
+func (w *waiter) turn(ctx context.Context) (bool, error) {
+	if !w.cfg.Fair {
+		return true, nil
+	}
+	timeout := 3*w.interval() + w.c.cfg.NodeTimeout
+	n, err := queueScript.Run(ctx, w.c.nodes[0], queueKeys(w.key),
+		w.token, time.Now().UnixMilli(), timeout.Milliseconds(), releaseChannel(w.key)).Int()
+	if err != nil {
+		return false, err
+	}
+	w.queued = true
+	return n == 1, nil
+}
+
+// wait 等待释放通知、兜底轮询间隔到达、deadline 到达或者 ctx 取消，只有 ctx 取消时返回错误。
+// 开启 PubSub 时第一次调用只建立订阅并立即返回：订阅生效前的释放可能已经错过，需要马上再试一次
+func (w *waiter) wait(ctx context.Context, deadline time.Time) error {
+	if w.cfg.PubSub && w.subs == nil {
+		w.subscribe(ctx)
+		return nil
+	}
+
+	sleep := w.interval()
+	if !w.cfg.PubSub {
+		// 轮询时带一点随机抖动
+		sleep += time.Duration(rand.Float64() * jitterFactor * float64(sleep))
+	}
+	if !deadline.IsZero() {
+		sleep = min(sleep, time.Until(deadline))
+	}
+	timer := time.NewTimer(sleep)
+	defer timer.Stop()
+
+	// 防止因 ctx 取消而多睡
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.notify:
+	case <-timer.C:
+	}
+	return nil
+}
+
+// subscribe 在所有节点上订阅释放通知并等待订阅确认，通知合并到 w.notify。
+// 订阅失败的节点只能依靠兜底轮询
+func (w *waiter) subscribe(ctx context.Context) {
+	for _, rdb := range w.c.nodes {
+		ps := rdb.Subscribe(ctx, releaseChannel(w.key))
+		w.subs = append(w.subs, ps)
+
+		sctx, cancel := context.WithTimeout(ctx, max(w.c.cfg.NodeTimeout, defaultNodeTimeout))
+		_, err := ps.Receive(sctx)
+		cancel()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for range ps.Channel() {
+				select {
+				case w.notify <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+}
+
+// close 取消订阅；公平模式下离开队列，没拿到锁时唤醒下一个等待者
+func (w *waiter) close() {
+	for _, ps := range w.subs {
+		ps.Close()
+	}
+	if w.queued {
+		giveUp := "0"
+		if !w.acquired {
+			giveUp = "1"
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), max(w.c.cfg.NodeTimeout, defaultNodeTimeout))
+		defer cancel()
+		leaveScript.Run(ctx, w.c.nodes[0], queueKeys(w.key), w.token, releaseChannel(w.key), giveUp)
+	}
+}
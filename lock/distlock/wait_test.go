@@ -0,0 +1,145 @@
+package dlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// 释放时通过 Pub/Sub 唤醒等待者，不需要等到兜底轮询
+func TestLockWakesOnRelease(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	l, err := c.TryLock(ctx, "res", time.Second)
+	if err != nil || l == nil {
+		t.Fatalf("TryLock = %v, %v", l, err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l.Unlock(ctx)
+	}()
+
+	start := time.Now()
+	l2, err := c.Lock(ctx, "res", WithFallbackInterval(10*time.Second), WithTryTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l2.Unlock(ctx)
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("waiter woke after %v, should be notified on release", d)
+	}
+}
+
+// 锁过期不会发通知，依靠兜底轮询发现
+func TestLockFallbackPolling(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	if l, err := c.TryLock(ctx, "res", time.Second); err != nil || l == nil {
+		t.Fatalf("TryLock = %v, %v", l, err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.Del("res")
+	}()
+	l, err := c.Lock(ctx, "res", WithFallbackInterval(20*time.Millisecond), WithTryTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Unlock(ctx)
+
+	// 关闭 PubSub 时按 RetryInterval 轮询
+	l, _ = c.TryLock(ctx, "res", time.Second)
+	_, err = c.Lock(ctx, "res", WithPubSub(false), WithRetryInterval(10*time.Millisecond), WithTryTimeout(50*time.Millisecond))
+	if !errors.Is(err, ErrAcquireTimeout) {
+		t.Fatalf("expected ErrAcquireTimeout, got %v", err)
+	}
+	l.Unlock(ctx)
+}
+
+// 公平模式下按到达顺序获取锁，放弃等待的调用会离开队列
+func TestFairLock(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	c := NewClient(rdb)
+	ctx := context.Background()
+
+	queue := queueKeys("res")
+	assertSameSlot(t, append([]string{"res"}, queue...)...)
+
+	holder, err := c.TryLock(ctx, "res", 5*time.Second)
+	if err != nil || holder == nil {
+		t.Fatalf("TryLock = %v, %v", holder, err)
+	}
+
+	// 放弃等待后离开队列，不会堵住后面的等待者
+	_, err = c.Lock(ctx, "res", WithFair(true), WithTryTimeout(30*time.Millisecond))
+	if !errors.Is(err, ErrAcquireTimeout) {
+		t.Fatalf("expected ErrAcquireTimeout, got %v", err)
+	}
+	if members, _ := s.ZMembers(queue[0]); len(members) != 0 {
+		t.Fatalf("queue should be empty, got %v", members)
+	}
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := c.Lock(ctx, "res", WithFair(true), WithTryTimeout(5*time.Second))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			l.Unlock(ctx)
+		}()
+		// 等第 i 个等待者入队后再启动下一个
+		for {
+			if members, _ := s.ZMembers(queue[0]); len(members) == i+1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if s.TTL(queue[2]) <= 0 {
+		t.Fatalf("arrival counter should expire")
+	}
+
+	holder.Unlock(ctx)
+	wg.Wait()
+	for i, v := range order {
+		if v != i {
+			t.Fatalf("acquire order = %v, want arrival order", order)
+		}
+	}
+	if len(order) != 3 {
+		t.Fatalf("order = %v", order)
+	}
+	// 队列空了之后不留下任何 key
+	for _, k := range queue {
+		if s.Exists(k) {
+			t.Fatalf("%s should be removed once the queue drains", k)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package leader 基于 dlock 的主节点选举：多个副本竞争同一把自动续约的锁，持有锁的副本就是 leader。
+// 适合只能在一个实例上执行的定时任务等场景。
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dlock "github.com/Nuyoahch/gopulse/lock/distlock"
+)
+
+// 对外可见的一些错误
+var (
+	ErrNoLeader      = errors.New("leader: no leader elected")
+	ErrNotObservable = errors.New("leader: locker does not support observing the holder")
+)
+
+// Options 控制选举行为
+type Options struct {
+	TTL           time.Duration // 领导权租约时长，leader 崩溃后最多经过这么久才会重新选举（默认 10s）
+	RenewInterval time.Duration // 续约间隔（默认 TTL/3）
+	RetryInterval time.Duration // 后端出错后重新竞选的间隔（默认 1s）
+	ResignDelay   time.Duration // 主动让位后多久重新参与竞选，给其他副本接手的机会（默认 TTL）
+
+	OnElected func(ctx context.Context) // 当选后在新的 goroutine 中调用，ctx 在失去领导权时取消
+	OnRevoked func()                    // 失去领导权（租约丢失、让位或者退出）且 OnElected 返回后调用
+}
+
+// 一些默认值
+const (
+	defaultTTL           = 10 * time.Second
+	defaultRetryInterval = time.Second
+)
+
+// DefaultOptions 默认配置
+func DefaultOptions() Options {
+	return Options{
+		TTL:           defaultTTL,
+		RetryInterval: defaultRetryInterval,
+	}
+}
+
+// Option 函数式编程
+type Option func(*Options)
+
+// WithTTL 初始化 TTL
+func WithTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.TTL = ttl
+	}
+}
+
+// WithRenewInterval 初始化 RenewInterval
+func WithRenewInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.RenewInterval = d
+	}
+}
+
+// WithRetryInterval 初始化 RetryInterval
+func WithRetryInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.RetryInterval = d
+	}
+}
+
+// WithResignDelay 初始化 ResignDelay
+func WithResignDelay(d time.Duration) Option {
+	return func(o *Options) {
+		o.ResignDelay = d
+	}
+}
+
+// WithOnElected 初始化 OnElected
+func WithOnElected(fn func(ctx context.Context)) Option {
+	return func(o *Options) {
+		o.OnElected = fn
+	}
+}
+
+// WithOnRevoked 初始化 OnRevoked
+func WithOnRevoked(fn func()) Option {
+	return func(o *Options) {
+		o.OnRevoked = fn
+	}
+}
+
+// Elector 一个副本的选举器，并发安全
+type Elector struct {
+	locker dlock.Locker
+	key    string
+	id     string
+	cfg    Options
+
+	leader atomic.Bool
+	mu     sync.Mutex
+	resign chan struct{} // 当前任期内关闭表示让位，不是 leader 时为 nil
+}
+
+// New 创建选举器：key 为选举使用的锁，id 为本副本的标识（写入锁的 owner，其他副本可以通过 Leader 看到）。
+// 同一个 key 的各个副本需要使用不同的 id
+func New(locker dlock.Locker, key, id string, opts ...Option) *Elector {
+	// 默认配置
+	cfg := DefaultOptions()
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	// base case
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+	if cfg.ResignDelay <= 0 {
+		cfg.ResignDelay = cfg.TTL
+	}
+	return &Elector{locker: locker, key: key, id: id, cfg: cfg}
+}
+
+// Run 持续参与竞选，直到 ctx 取消；退出前如果是 leader 会先让出领导权。
+// 后端出错时按 RetryInterval 重试，只在 ctx 取消时返回 ctx.Err()
+func (e *Elector) Run(ctx context.Context) error {
+	for {
+		l, err := e.locker.Lock(ctx, e.key,
+			dlock.WithOwner(e.id),
+			dlock.WithTTL(e.cfg.TTL),
+			dlock.WithAutoRenew(true),
+			dlock.WithRenewInterval(e.cfg.RenewInterval),
+		)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !sleep(ctx, e.cfg.RetryInterval) {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		if resigned := e.lead(ctx, l); resigned && !sleep(ctx, e.cfg.ResignDelay) {
+			return ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// lead 执行一个任期：调用 OnElected，直到租约丢失、让位或者 ctx 取消，
+// 等 OnElected 返回后再释放锁，避免新旧 leader 的任务同时执行。返回是否是主动让位
+func (e *Elector) lead(ctx context.Context, l *dlock.Lock) bool {
+	resign := make(chan struct{})
+	e.mu.Lock()
+	e.resign = resign
+	e.mu.Unlock()
+	e.leader.Store(true)
+
+	term, cancel := context.WithCancel(l.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if e.cfg.OnElected != nil {
+			e.cfg.OnElected(term)
+		}
+	}()
+
+	resigned := false
+	select {
+	case <-l.Done():
+	case <-ctx.Done():
+	case <-resign:
+		resigned = true
+	}
+	e.leader.Store(false)
+	e.mu.Lock()
+	e.resign = nil
+	e.mu.Unlock()
+
+	cancel()
+	<-done
+	unlockCtx, stop := context.WithTimeout(context.WithoutCancel(ctx), e.cfg.TTL)
+	l.Unlock(unlockCtx)
+	stop()
+	if e.cfg.OnRevoked != nil {
+		e.cfg.OnRevoked()
+	}
+	return resigned
+}
+
+// IsLeader 本副本当前是否是 leader
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// ID 返回本副本的标识
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// Resign 主动让出领导权，等待 ResignDelay 后重新参与竞选；不是 leader 时什么也不做
+func (e *Elector) Resign() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.resign != nil {
+		close(e.resign)
+		e.resign = nil
+	}
+}
+
+// Leader 返回当前 leader 的标识，没有 leader 时返回 ErrNoLeader。
+// 需要 Locker 实现 dlock.Observer（Client、MemoryLocker、SQLLocker 都实现了）
+func (e *Elector) Leader(ctx context.Context) (string, error) {
+	obs, ok := e.locker.(dlock.Observer)
+	if !ok {
+		return "", ErrNotObservable
+	}
+	owner, held, err := obs.Holder(ctx, e.key)
+	if err != nil {
+		return "", err
+	}
+	if !held {
+		return "", ErrNoLeader
+	}
+	return owner, nil
+}
+
+// sleep 等待 d 或者 ctx 取消，ctx 取消时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,110 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	dlock "github.com/Nuyoahch/gopulse/lock/distlock"
+)
+
+// waitFor 等待 cond 成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 同一时间只有一个 leader，让位、退出后其他副本接手
+func TestElection(t *testing.T) {
+	locker := dlock.NewMemoryLocker()
+	var running atomic.Int32
+	newElector := func(id string) *Elector {
+		return New(locker, "cron", id,
+			WithTTL(time.Second),
+			WithResignDelay(200*time.Millisecond),
+			WithOnElected(func(ctx context.Context) {
+				if running.Add(1) != 1 {
+					t.Error("two leaders at the same time")
+				}
+				<-ctx.Done()
+				running.Add(-1)
+			}),
+		)
+	}
+	a, b := newElector("a"), newElector("b")
+	ctxA, stopA := context.WithCancel(context.Background())
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	errA := make(chan error, 1)
+	go func() { errA <- a.Run(ctxA) }()
+	go b.Run(ctxB)
+
+	waitFor(t, "a leader", func() bool { return a.IsLeader() || b.IsLeader() })
+	first, second := a, b
+	if b.IsLeader() {
+		first, second = b, a
+	}
+	if id, err := first.Leader(context.Background()); err != nil || id != first.ID() {
+		t.Fatalf("Leader = %q, %v, want %q", id, err, first.ID())
+	}
+
+	// 让位后另一个副本当选
+	first.Resign()
+	waitFor(t, "takeover after resign", second.IsLeader)
+	if first.IsLeader() {
+		t.Fatal("resigned elector should not be leader")
+	}
+
+	// 退出时让出领导权，由另一个副本接手
+	stopA()
+	if err := <-errA; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v", err)
+	}
+	waitFor(t, "b leader after a exits", b.IsLeader)
+}
+
+// 租约丢失时取消 OnElected 的 ctx 并调用 OnRevoked，之后重新竞选
+func TestLeaseLost(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+
+	var elected, revoked atomic.Int32
+	e := New(dlock.NewClient(rdb), "cron", "a",
+		WithTTL(time.Second),
+		WithRenewInterval(10*time.Millisecond),
+		WithOnElected(func(ctx context.Context) {
+			elected.Add(1)
+			<-ctx.Done()
+		}),
+		WithOnRevoked(func() { revoked.Add(1) }),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx)
+
+	waitFor(t, "elected", e.IsLeader)
+	if _, err := e.Leader(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s.Del("cron")
+	waitFor(t, "revoked", func() bool { return revoked.Load() == 1 })
+	waitFor(t, "re-elected", func() bool { return elected.Load() == 2 && e.IsLeader() })
+
+	cancel()
+	waitFor(t, "revoked on exit", func() bool { return revoked.Load() == 2 })
+	if _, err := e.Leader(context.Background()); !errors.Is(err, ErrNoLeader) {
+		t.Fatalf("expected ErrNoLeader, got %v", err)
+	}
+}
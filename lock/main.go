@@ -19,7 +19,8 @@ func main() {
 
 	client := dlock.NewClient(rdb)
 
-	ctx := context.Background()
+	// 带上持有者标识，同一个 owner 的后续调用才可以重入
+	ctx := dlock.NewOwnerContext(context.Background(), "worker-1")
 
 	// 获取一把锁，TTL=5s，获取最多等 3s，自动续约
 	lock, err := client.Lock(
@@ -36,7 +37,7 @@ func main() {
 
 	fmt.Println("got lock:", lock.Key())
 
-	// 演示“可重入”：同一 goroutine 再次获取同一把锁
+	// 演示“可重入”：同一个 owner 再次获取同一把锁
 	lock2, err := client.Lock(ctx, "demo:lock")
 	if err != nil {
 		log.Fatal("reenter lock failed:", err)
@@ -45,8 +46,12 @@ func main() {
 
 	fmt.Println("reentered lock")
 
-	// 模拟一个比较长的任务，超过 TTL，但因为有自动续约不会丢锁
-	time.Sleep(12 * time.Second)
+	// 模拟一个比较长的任务，超过 TTL，但因为有自动续约不会丢锁；万一丢锁，立即中止
+	select {
+	case <-lock.Done():
+		log.Fatal("lock lost:", context.Cause(lock.Context()))
+	case <-time.After(12 * time.Second):
+	}
 
 	fmt.Println("business done")
 }